@@ -0,0 +1,122 @@
+package v1alpha1
+
+// GameServerState is where a GameServer is in its lifecycle. Allowed
+// transitions, besides moving to the final Shutdown state from anywhere:
+//
+//	Creating     → Starting, Scheduled, RequestReady, Ready, Unhealthy
+//	Starting     → Scheduled, RequestReady, Ready, Unhealthy
+//	Scheduled    → RequestReady, Ready, Unhealthy
+//	RequestReady → Ready, Reserved, Allocated, Unhealthy
+//	Ready        → RequestReady, Reserved, Allocated, Unhealthy
+//	Reserved     → Ready, RequestReady, Allocated, Unhealthy
+//	Allocated    → Ready, RequestReady, Unhealthy
+//	Unhealthy    → Starting (new Pod), or back to RequestReady, Ready,
+//	               Reserved or Allocated when it recovers in place
+//
+// Start-up states may be skipped: observations arrive at their own pace. A
+// GameServer without a state yet (new, or created by an older operator) may
+// take any state.
+//
+// +kubebuilder:validation:Enum=Creating;Starting;Scheduled;RequestReady;Ready;Reserved;Allocated;Unhealthy;Shutdown
+type GameServerState string
+
+const (
+	// The GameServer exists; its Pod has not been created yet.
+	GameServerStateCreating GameServerState = "Creating"
+	// The Pod was created and waits for a node.
+	GameServerStateStarting GameServerState = "Starting"
+	// The Pod is bound to a node; its containers are not running yet.
+	GameServerStateScheduled GameServerState = "Scheduled"
+	// The Pod runs; the server has not answered a status probe or sent a
+	// ready heartbeat yet.
+	GameServerStateRequestReady GameServerState = "RequestReady"
+	// The server reports in and can be allocated.
+	GameServerStateReady GameServerState = "Ready"
+	// Healthy but held back from allocation.
+	GameServerStateReserved GameServerState = "Reserved"
+	// Handed to a match by a GameServerAllocation.
+	GameServerStateAllocated GameServerState = "Allocated"
+	// Probes fail, heartbeats stopped, or the Pod failed or vanished.
+	GameServerStateUnhealthy GameServerState = "Unhealthy"
+	// Being deleted, or the Pod exited. Final.
+	GameServerStateShutdown GameServerState = "Shutdown"
+)
+
+// Annotations that reserve a Ready GameServer for a while, e.g. while a party
+// forms. The reservation lapses on its own at ReservedUntilAnnotation (an
+// RFC3339 time); ReservedByAnnotation names the holder.
+const (
+	ReservedByAnnotation    = "game.example.com/reserved-by"
+	ReservedUntilAnnotation = "game.example.com/reserved-until"
+)
+
+// gameServerTransitions lists, for every state, the states it may move to
+// besides Shutdown.
+var gameServerTransitions = map[GameServerState][]GameServerState{
+	GameServerStateCreating:     {GameServerStateStarting, GameServerStateScheduled, GameServerStateRequestReady, GameServerStateReady, GameServerStateUnhealthy},
+	GameServerStateStarting:     {GameServerStateScheduled, GameServerStateRequestReady, GameServerStateReady, GameServerStateUnhealthy},
+	GameServerStateScheduled:    {GameServerStateRequestReady, GameServerStateReady, GameServerStateUnhealthy},
+	GameServerStateRequestReady: {GameServerStateReady, GameServerStateReserved, GameServerStateAllocated, GameServerStateUnhealthy},
+	GameServerStateReady:        {GameServerStateRequestReady, GameServerStateReserved, GameServerStateAllocated, GameServerStateUnhealthy},
+	GameServerStateReserved:     {GameServerStateReady, GameServerStateRequestReady, GameServerStateAllocated, GameServerStateUnhealthy},
+	GameServerStateAllocated:    {GameServerStateReady, GameServerStateRequestReady, GameServerStateUnhealthy},
+	GameServerStateUnhealthy:    {GameServerStateStarting, GameServerStateRequestReady, GameServerStateReady, GameServerStateReserved, GameServerStateAllocated},
+}
+
+// CanTransitionTo reports whether a GameServer in state s may move to next.
+// Staying in the same state is always allowed.
+func (s GameServerState) CanTransitionTo(next GameServerState) bool {
+	if s == next || s == "" {
+		return true
+	}
+	if s == GameServerStateShutdown {
+		return false
+	}
+	if next == GameServerStateShutdown {
+		return true
+	}
+	for _, t := range gameServerTransitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReady is true for states in which the server is up and reporting in.
+func (s GameServerState) IsReady() bool {
+	return s == GameServerStateReady || s == GameServerStateReserved || s == GameServerStateAllocated
+}
+
+// IsStarting is true before a server first becomes Ready.
+func (s GameServerState) IsStarting() bool {
+	switch s {
+	case "", GameServerStateCreating, GameServerStateStarting, GameServerStateScheduled, GameServerStateRequestReady:
+		return true
+	}
+	return false
+}
+
+// SetState moves the status to next if the transition is allowed and keeps
+// the deprecated Phase in step. It reports whether the state is now next.
+func (st *GameServerStatus) SetState(next GameServerState) bool {
+	if !st.State.CanTransitionTo(next) {
+		return false
+	}
+	st.State = next
+	st.Phase = legacyPhase(next)
+	return true
+}
+
+// legacyPhase is the Phase older clients expect for a state.
+func legacyPhase(s GameServerState) string {
+	switch {
+	case s.IsReady():
+		return "Running"
+	case s == GameServerStateUnhealthy:
+		return "Unreachable"
+	case s == GameServerStateShutdown:
+		return "Terminating"
+	}
+	return "Pending"
+}
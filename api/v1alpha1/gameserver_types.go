@@ -5,6 +5,30 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Status probe protocols.
+const (
+	StatusProbeHTTP      = "HTTP"         // GET pollPath → {"players","maxPlayers"} (default)
+	StatusProbeA2S       = "A2S"          // Valve A2S_INFO over UDP
+	StatusProbeMinecraft = "MinecraftSLP" // Minecraft Java Server List Ping over TCP
+	StatusProbeTCP       = "TCP"          // TCP connect only; no player counts
+)
+
+// How a server's state reaches the operator.
+const (
+	StatusModePoll = "Poll" // the controller polls with the StatusProbe (default)
+	StatusModePush = "Push" // the server pushes heartbeats; see internal/heartbeat
+	StatusModeBoth = "Both" // both; polling decides reachability, pushes add fresher counts
+)
+
+// StatusProbe selects how the operator asks a server for its player counts.
+type StatusProbe struct {
+	// HTTP (default), A2S, MinecraftSLP or TCP.
+	// +kubebuilder:validation:Enum=HTTP;A2S;MinecraftSLP;TCP
+	Type string `json:"type,omitempty"`
+	// Query port when it differs from the game port (e.g. a separate A2S port).
+	Port *int32 `json:"port,omitempty"`
+}
+
 // GameServerSpec defines the desired state of a single game server.
 type GameServerSpec struct {
 	Image        string                      `json:"image,omitempty"`
@@ -13,23 +37,55 @@ type GameServerSpec struct {
 	Env          []corev1.EnvVar             `json:"env,omitempty"`
 	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`
 	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+	// Full Pod template (tolerations, volumes, sidecars, ...). The controller only
+	// overlays what it owns: GAME_PORT, the game port, owner labels, hostNetwork and
+	// a default readiness probe. Image/Env/Resources/NodeSelector above still win
+	// over the template when set.
+	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+	// Name of the game container inside Template (default "server", else the first container).
+	Container string `json:"container,omitempty"`
+	// How player counts are polled; nil means HTTP JSON on PollPath.
+	StatusProbe *StatusProbe `json:"statusProbe,omitempty"`
+	// Poll (default), Push or Both; see StatusMode* constants.
+	// +kubebuilder:validation:Enum=Poll;Push;Both
+	StatusMode string `json:"statusMode,omitempty"`
+	// Push mode: the server is marked Unreachable when no heartbeat arrived for this long (default 30).
+	// +kubebuilder:validation:Minimum=1
+	HeartbeatTimeoutSeconds int32 `json:"heartbeatTimeoutSeconds,omitempty"`
 }
 
 // GameServerStatus reflects observed state.
 type GameServerStatus struct {
-	Players    int32              `json:"players,omitempty"`
-	MaxPlayers int32              `json:"maxPlayers,omitempty"`
-	Endpoint   string             `json:"endpoint,omitempty"`
-	NodeName   string             `json:"nodeName,omitempty"`
-	LastPolled *metav1.Time       `json:"lastPolled,omitempty"`
-	Phase      string             `json:"phase,omitempty"`     // Pending|Running|Unreachable|Error|Terminating
+	Players    int32        `json:"players,omitempty"`
+	MaxPlayers int32        `json:"maxPlayers,omitempty"`
+	Endpoint   string       `json:"endpoint,omitempty"`
+	NodeName   string       `json:"nodeName,omitempty"`
+	LastPolled *metav1.Time `json:"lastPolled,omitempty"`
+	// Last accepted heartbeat (Push/Both modes).
+	LastHeartbeat *metav1.Time `json:"lastHeartbeat,omitempty"`
+	// Lifecycle state; see GameServerState for the allowed transitions.
+	State GameServerState `json:"state,omitempty"`
+	// Who holds the server in Reserved, and until when; mirrors the
+	// reservation annotations while the reservation is on.
+	ReservedBy    string       `json:"reservedBy,omitempty"`
+	ReservedUntil *metav1.Time `json:"reservedUntil,omitempty"`
+	// Deprecated: use State. Pending|Running|Unreachable|Terminating, derived from State.
+	Phase      string             `json:"phase,omitempty"`
 	ZeroSince  *metav1.Time       `json:"zeroSince,omitempty"` // when players last became zero
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// True while only a liveness probe (TCP) reports in: Players is not a
+	// count then, and the server is never taken for empty.
+	PlayersUnknown bool `json:"playersUnknown,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=gs
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.spec.port`
+// +kubebuilder:printcolumn:name="Players",type=integer,JSONPath=`.status.players`
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.nodeName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type GameServer struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
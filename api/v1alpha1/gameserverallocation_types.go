@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Allocation outcomes reported in GameServerAllocationStatus.State.
+const (
+	AllocationStateAllocated   = "Allocated"   // a GameServer was handed out
+	AllocationStateUnAllocated = "UnAllocated" // no Ready server matched
+	AllocationStateContention  = "Contention"  // every candidate was taken by someone else first
+)
+
+// GameServerAllocationSpec selects which GameServer to hand out.
+// It is create-only: once the controller has filled in Status the request is done.
+type GameServerAllocationSpec struct {
+	// GSDeployment to allocate from (same namespace).
+	GSDeployment string `json:"gsDeployment"`
+	// Optional extra label selector applied to the fleet's GameServers.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// GameServerAllocationStatus is the answer to the allocation request.
+type GameServerAllocationStatus struct {
+	State          string `json:"state,omitempty"` // Allocated|UnAllocated|Contention
+	GameServerName string `json:"gameServerName,omitempty"`
+	Address        string `json:"address,omitempty"` // host IP of the server's node
+	Port           int32  `json:"port,omitempty"`
+	NodeName       string `json:"nodeName,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gsa
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="GameServer",type=string,JSONPath=`.status.gameServerName`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+// +kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.status.port`
+type GameServerAllocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GameServerAllocationSpec   `json:"spec,omitempty"`
+	Status            GameServerAllocationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+type GameServerAllocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameServerAllocation `json:"items"`
+}
@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RevisionLabel carries the template revision on a GameServerSet and on
+// each of its GameServers.
+const RevisionLabel = "game.example.com/revision"
+
+// GameServerSetSpec is one revision of a GSDeployment's server template.
+// Sets are created and scaled by their GSDeployment, like ReplicaSets under a
+// Deployment; edit the GSDeployment, not the set.
+type GameServerSetSpec struct {
+	// Hash of Template; also the set's RevisionLabel.
+	Revision string `json:"revision"`
+	// Spec of every GameServer in the set; each server gets its own port.
+	Template GameServerSpec `json:"template"`
+}
+
+// GameServerSetStatus counts the set's GameServers.
+type GameServerSetStatus struct {
+	Replicas          int32 `json:"replicas,omitempty"`
+	ReadyReplicas     int32 `json:"readyReplicas,omitempty"`
+	AllocatedReplicas int32 `json:"allocatedReplicas,omitempty"`
+	DrainingReplicas  int32 `json:"drainingReplicas,omitempty"`
+	Players           int32 `json:"players,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gss
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.status.replicas`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Allocated",type=integer,JSONPath=`.status.allocatedReplicas`
+// +kubebuilder:printcolumn:name="Draining",type=integer,JSONPath=`.status.drainingReplicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GameServerSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GameServerSetSpec   `json:"spec,omitempty"`
+	Status            GameServerSetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+type GameServerSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameServerSet `json:"items"`
+}
@@ -15,5 +15,7 @@ func init() {
 	SchemeBuilder.Register(
 		&GameServer{}, &GameServerList{},
 		&GSDeployment{}, &GSDeploymentList{},
+		&GameServerAllocation{}, &GameServerAllocationList{},
+		&GameServerSet{}, &GameServerSetList{},
 	)
 }
@@ -1,8 +1,10 @@
 package v1alpha1
 
 import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type PortRange struct {
@@ -10,16 +12,256 @@ type PortRange struct {
 	End   int32 `json:"end"`
 }
 
+// Port allocation policies.
+const (
+	// Every GameServer in the fleet gets its own port (fleet size <= range size).
+	PortPolicyCluster = "Cluster"
+	// Ports only need to be unique per node (pods use hostNetwork), so each port
+	// can be reused once on every eligible node.
+	PortPolicyPerNode = "PerNode"
+)
+
+// Ports used by the fleet on one node.
+type NodePortUsage struct {
+	NodeName string  `json:"nodeName"` // empty: not scheduled yet
+	Ports    []int32 `json:"ports,omitempty"`
+}
+
+// Update strategies.
+const (
+	// Drain every outdated server once it is empty, surging replacements (default).
+	UpdateStrategyNoDisruption = "NoDisruption"
+	// Move the fleet to the new revision in steps (canary.steps), keeping the
+	// rest on the stable revision until the steps are done.
+	UpdateStrategyCanary = "Canary"
+	// Bring up a full fleet of the new revision next to the old one, switch
+	// allocations over at once, then drain the old fleet.
+	UpdateStrategyBlueGreen = "BlueGreen"
+	// Delete every outdated server at once, players or not, then create new
+	// ones. For fleets without live players (dev, QA).
+	UpdateStrategyRecreate = "Recreate"
+	// Replace outdated servers in batches within maxSurge/maxUnavailable,
+	// players or not.
+	UpdateStrategyRollingUpdate = "RollingUpdate"
+)
+
 // Minimal rollout knobs (PoC)
 type UpdateStrategy struct {
-	// Only "NoDisruption" supported in PoC; leave empty to default.
+	// NoDisruption (default), Canary, BlueGreen, Recreate or RollingUpdate;
+	// see UpdateStrategy* constants.
 	Type string `json:"type,omitempty"`
 	// If a server stays busy, we stop waiting after this timeout (seconds).
 	DrainTimeoutSeconds int32 `json:"drainTimeoutSeconds,omitempty"`
-	// How many extra servers we can add during rollout (above MinReplicas).
-	MaxSurge int32 `json:"maxSurge,omitempty"`
-	// How many ready servers we can have unavailable during rollout.
-	MaxUnavailable int32 `json:"maxUnavailable,omitempty"`
+	// How many extra servers we can add during rollout, as a number or a
+	// percentage of the fleet (rounded up). Default 2. An explicit 0 is kept
+	// when maxUnavailable is above 0; with maxUnavailable 0 or unset it still
+	// means 2, as it did when this field was a plain number.
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// How many ready servers we can have unavailable during rollout, as a number
+	// or a percentage of the fleet (rounded down). Default 0.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// Canary: the steps a new revision goes through.
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+	// BlueGreen: when allocations switch to the new revision.
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+}
+
+// BlueGreenStrategy controls the switch of a BlueGreen rollout.
+type BlueGreenStrategy struct {
+	// Switch allocations as soon as the preview fleet is Ready (default true).
+	// When false, wait until the GSDeployment is annotated
+	// game.example.com/promote=true.
+	AutoPromotionEnabled *bool `json:"autoPromotionEnabled,omitempty"`
+}
+
+// CanaryStrategy lists the steps of a canary rollout. After the last step
+// the whole fleet moves to the new revision.
+type CanaryStrategy struct {
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+}
+
+// CanaryStep sets the canary's share of the fleet or pauses; exactly one
+// field is set.
+type CanaryStep struct {
+	// Percentage of the fleet to run on the new revision; the step is done
+	// once that many new servers are Ready.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SetWeight *int32 `json:"setWeight,omitempty"`
+	// Hold the current split for a while, or until promoted.
+	Pause *CanaryPause `json:"pause,omitempty"`
+}
+
+// CanaryPause holds a canary rollout.
+type CanaryPause struct {
+	// How long to hold; unset waits until the GSDeployment is annotated
+	// game.example.com/promote=true.
+	// +kubebuilder:validation:Minimum=1
+	DurationSeconds *int32 `json:"durationSeconds,omitempty"`
+}
+
+// Autoscaling policies.
+const (
+	// Add one server when any server reaches scaleUpThresholdPercent; remove
+	// servers idle for scaleDownZeroSeconds (default).
+	ScalingPolicyThreshold = "Threshold"
+	// Keep bufferSize Ready, unallocated, empty servers waiting.
+	ScalingPolicyBuffer = "Buffer"
+	// Ask an external service (scaling.webhook) for the replica count; falls
+	// back to Threshold while it cannot be reached.
+	ScalingPolicyWebhook = "Webhook"
+	// Size the fleet so total players / total capacity approaches
+	// targetUtilizationPercent.
+	ScalingPolicyUtilization = "Utilization"
+)
+
+// Scaling selects how the fleet size follows demand. minReplicas and
+// maxReplicas bound every policy.
+type Scaling struct {
+	// Threshold (default), Buffer, Webhook or Utilization; see ScalingPolicy* constants.
+	// +kubebuilder:validation:Enum=Threshold;Buffer;Webhook;Utilization
+	Policy string `json:"policy,omitempty"`
+	// Buffer: empty servers to keep ready, as a number or a percentage of the
+	// fleet (e.g. "20%": a fifth of all servers stay free). Default 2.
+	BufferSize *intstr.IntOrString `json:"bufferSize,omitempty"`
+	// Webhook: where to POST the fleet review (url, or an in-cluster service)
+	// and the optional caBundle to trust.
+	Webhook *admissionregistrationv1.WebhookClientConfig `json:"webhook,omitempty"`
+	// Utilization: fleet-wide share of player slots in use to aim for. Default 70.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetUtilizationPercent int32 `json:"targetUtilizationPercent,omitempty"`
+	// Buffer, Webhook and Utilization: most servers added in one pass; unset
+	// means as many as the target needs.
+	// +kubebuilder:validation:Minimum=1
+	MaxScaleUpStep *int32 `json:"maxScaleUpStep,omitempty"`
+	// Stabilization windows and rate limits applied to every policy's
+	// decision, like an HPA's behavior. Unset means none.
+	Behavior *ScalingBehavior `json:"behavior,omitempty"`
+}
+
+// ScalingBehavior configures scale up and scale down separately.
+type ScalingBehavior struct {
+	ScaleUp   *ScalingRules `json:"scaleUp,omitempty"`
+	ScaleDown *ScalingRules `json:"scaleDown,omitempty"`
+}
+
+// How to combine several ScalingRatePolicies.
+const (
+	// Use the policy allowing the biggest change (default).
+	SelectPolicyMax = "Max"
+	// Use the policy allowing the smallest change.
+	SelectPolicyMin = "Min"
+	// Never scale in this direction.
+	SelectPolicyDisabled = "Disabled"
+)
+
+// ScalingRules limits scaling in one direction.
+type ScalingRules struct {
+	// Recommendations from the past window considered before scaling: scale
+	// up only to the lowest, scale down only to the highest. Default 0.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3600
+	StabilizationWindowSeconds *int32 `json:"stabilizationWindowSeconds,omitempty"`
+	// Max (default), Min or Disabled; see SelectPolicy* constants.
+	// +kubebuilder:validation:Enum=Max;Min;Disabled
+	SelectPolicy string `json:"selectPolicy,omitempty"`
+	// Rate limits, e.g. at most 2 servers or 10% per 60s. None means unlimited.
+	Policies []ScalingRatePolicy `json:"policies,omitempty"`
+}
+
+// Rate policy units.
+const (
+	ScalingRateServers = "Servers"
+	ScalingRatePercent = "Percent"
+)
+
+// ScalingRatePolicy allows Value servers (or percent of the fleet) to be
+// added or removed per PeriodSeconds.
+type ScalingRatePolicy struct {
+	// Servers or Percent.
+	// +kubebuilder:validation:Enum=Servers;Percent
+	Type string `json:"type"`
+	// +kubebuilder:validation:Minimum=1
+	Value int32 `json:"value"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1800
+	PeriodSeconds int32 `json:"periodSeconds"`
+}
+
+// ScalingSchedule overrides the fleet's bounds during a recurring window, e.g.
+// to pre-warm capacity before the evening peak.
+type ScalingSchedule struct {
+	// Unique within the fleet; reported in status.activeSchedule.
+	Name string `json:"name"`
+	// When the window opens, in standard 5-field cron syntax ("0 18 * * 1-5").
+	Schedule string `json:"schedule"`
+	// IANA time zone Schedule is read in (e.g. "Europe/Berlin"); default UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// How long the window stays open after each start.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=604800
+	DurationSeconds int32 `json:"durationSeconds"`
+	// Overrides while the window is open; unset fields keep the spec value.
+	MinReplicas *int32              `json:"minReplicas,omitempty"`
+	MaxReplicas *int32              `json:"maxReplicas,omitempty"`
+	BufferSize  *intstr.IntOrString `json:"bufferSize,omitempty"`
+}
+
+// RollbackConfig names the revision to roll back to.
+type RollbackConfig struct {
+	// Revision number from status.revisionHistory; 0 means the one before the
+	// current revision.
+	// +kubebuilder:validation:Minimum=0
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// CanaryStatus reports a Canary rollout.
+type CanaryStatus struct {
+	// Revision (template hash) being rolled out; a newer one restarts the steps.
+	Revision string `json:"revision"`
+	// Index of the step in progress; the number of steps once all are done.
+	CurrentStepIndex int32 `json:"currentStepIndex"`
+	// Share of the fleet the current step puts on the new revision.
+	Weight int32 `json:"weight"`
+	// Servers of the new revision, and of the stable revision still serving.
+	CanaryReplicas int32 `json:"canaryReplicas"`
+	StableReplicas int32 `json:"stableReplicas"`
+	// When the current pause step began.
+	PauseStartTime *metav1.Time `json:"pauseStartTime,omitempty"`
+	// True while a pause step waits for game.example.com/promote.
+	AwaitingPromotion bool `json:"awaitingPromotion,omitempty"`
+}
+
+// BlueGreenStatus reports a BlueGreen rollout.
+type BlueGreenStatus struct {
+	// Revision (template hash) allocations go to.
+	ActiveRevision string `json:"activeRevision,omitempty"`
+	// Revision being brought up next to the active one; empty when none.
+	PreviewRevision string `json:"previewRevision,omitempty"`
+	// Servers the preview fleet is sized to (the active fleet's Ready count),
+	// and how many of them are Ready.
+	PreviewTargetReplicas int32 `json:"previewTargetReplicas,omitempty"`
+	PreviewReadyReplicas  int32 `json:"previewReadyReplicas,omitempty"`
+	// True while a Ready preview waits for game.example.com/promote.
+	AwaitingPromotion bool `json:"awaitingPromotion,omitempty"`
+}
+
+// RevisionRecord is one template revision kept in the fleet's history.
+type RevisionRecord struct {
+	// Counts up from 1 with every new template; a revision rolled back to
+	// gets the next number.
+	Revision int64 `json:"revision"`
+	// GameServerSet holding the revision's template and servers.
+	GameServerSet string `json:"gameServerSet"`
+	// The GSDeployment's kubernetes.io/change-cause annotation when the
+	// revision became current.
+	ChangeCause string `json:"changeCause,omitempty"`
+	// Servers of this revision still running.
+	Replicas int32 `json:"replicas"`
+	// When the revision was first created.
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
 }
 
 // Tiny inline config
@@ -28,27 +270,86 @@ type Parameters struct {
 }
 
 type GSDeploymentSpec struct {
-	Image                   string                      `json:"image,omitempty"`
-	PollPath                string                      `json:"pollPath,omitempty"`
-	MinReplicas             int32                       `json:"minReplicas"`
-	MaxReplicas             int32                       `json:"maxReplicas"`
-	ScaleUpThresholdPercent int32                       `json:"scaleUpThresholdPercent,omitempty"` // default 80
-	ScaleDownZeroSeconds    int32                       `json:"scaleDownZeroSeconds,omitempty"`    // default 60
-	PortRange               PortRange                   `json:"portRange"`
-	NodeSelector            map[string]string           `json:"nodeSelector,omitempty"`
-	Resources               corev1.ResourceRequirements `json:"resources,omitempty"`
-	Env                     []corev1.EnvVar             `json:"env,omitempty"`
+	Image                   string    `json:"image,omitempty"`
+	PollPath                string    `json:"pollPath,omitempty"`
+	MinReplicas             int32     `json:"minReplicas"`
+	MaxReplicas             int32     `json:"maxReplicas"`
+	ScaleUpThresholdPercent int32     `json:"scaleUpThresholdPercent,omitempty"` // default 80
+	ScaleDownZeroSeconds    int32     `json:"scaleDownZeroSeconds,omitempty"`    // default 60
+	PortRange               PortRange `json:"portRange"`
+	// Cluster (default) or PerNode; see PortPolicy* constants.
+	PortPolicy   string                      `json:"portPolicy,omitempty"`
+	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`
+	Env          []corev1.EnvVar             `json:"env,omitempty"`
+	// Pod template copied into every GameServer (see GameServerSpec.Template).
+	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+	// Name of the game container inside Template.
+	Container string `json:"container,omitempty"`
+	// Status probe protocol copied into every GameServer.
+	StatusProbe *StatusProbe `json:"statusProbe,omitempty"`
+	// Poll (default), Push or Both, copied into every GameServer.
+	// +kubebuilder:validation:Enum=Poll;Push;Both
+	StatusMode string `json:"statusMode,omitempty"`
+	// Heartbeat staleness timeout copied into every GameServer (default 30).
+	// +kubebuilder:validation:Minimum=1
+	HeartbeatTimeoutSeconds int32 `json:"heartbeatTimeoutSeconds,omitempty"`
+	// Autoscaling policy; nil means Threshold.
+	Scaling *Scaling `json:"scaling,omitempty"`
+	// Recurring windows that override min/maxReplicas and bufferSize; the
+	// first open window in list order wins.
+	Schedules []ScalingSchedule `json:"schedules,omitempty"`
 	// NEW: rollout policy (simple PoC defaults)
 	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+	// Freeze rollouts: spec changes still get a revision, but outdated
+	// servers are neither drained nor replaced until unpaused.
+	Paused bool `json:"paused,omitempty"`
+	// Freeze autoscaling: the scaling policy neither adds nor removes servers.
+	// minReplicas is still kept.
+	AutoscalingPaused bool `json:"autoscalingPaused,omitempty"`
 	// NEW: tiny inline knobs (e.g., maxPlayers)
 	Parameters *Parameters `json:"parameters,omitempty"`
+	// Old revisions kept for rollback once all their servers are gone
+	// (default 10).
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// Set to roll the servers back to an earlier revision: the controller
+	// copies that revision's template into this spec, clears rollbackTo and
+	// rolls the fleet like any other change.
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
 }
 
 type GSDeploymentStatus struct {
-	Replicas       int32              `json:"replicas,omitempty"`
-	ReadyReplicas  int32              `json:"readyReplicas,omitempty"`
-	AllocatedPorts []int32            `json:"allocatedPorts,omitempty"`
-	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+	// Generation of the spec the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Replicas           int32 `json:"replicas,omitempty"`
+	ReadyReplicas      int32 `json:"readyReplicas,omitempty"`
+	// Servers of updateRevision.
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// Servers marked draining (outdated, or on their way out).
+	DrainingReplicas int32 `json:"drainingReplicas,omitempty"`
+	// Ready (or Reserved/Allocated) servers that are not draining.
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// Current (non-draining) servers the scaling policy asked for in the last pass.
+	DesiredReplicas int32           `json:"desiredReplicas,omitempty"`
+	AllocatedPorts  []int32         `json:"allocatedPorts,omitempty"`
+	NodePortUsage   []NodePortUsage `json:"nodePortUsage,omitempty"`
+	// Name of the schedule currently overriding the spec, if any.
+	ActiveSchedule string `json:"activeSchedule,omitempty"`
+	// Revision (template hash) the whole fleet last ran. It catches up with
+	// updateRevision when a rollout completes; Canary and BlueGreen rollouts
+	// keep it serving meanwhile.
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// Template hash new servers are created from; its GameServerSet is
+	// named <gsdeployment>-<updateRevision>.
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// Progress of a Canary rollout.
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// Active and preview revisions of a BlueGreen rollout.
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+	// The current revision and those kept for rollback, newest first.
+	RevisionHistory []RevisionRecord   `json:"revisionHistory,omitempty"`
+	Conditions      []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
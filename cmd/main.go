@@ -3,9 +3,14 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"time"
 
 	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
 	"github.com/ahbeigi/gameserver-operator/internal/controller"
+	"github.com/ahbeigi/gameserver-operator/internal/heartbeat"
+	"github.com/ahbeigi/gameserver-operator/internal/poller"
+	webhookgamev1alpha1 "github.com/ahbeigi/gameserver-operator/internal/webhook/v1alpha1"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -29,11 +34,19 @@ func init() {
 }
 
 func main() {
-	var metricsAddr, probeAddr string
+	var metricsAddr, probeAddr, heartbeatAddr, heartbeatURL, heartbeatCertDir string
 	var enableLeaderElection bool
+	var pollInterval time.Duration
+	var pollWorkers int
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "metrics bind address")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "health probe bind address")
+	flag.StringVar(&heartbeatAddr, "heartbeat-bind-address", ":8090", "heartbeat server bind address; 0 disables it")
+	flag.StringVar(&heartbeatURL, "heartbeat-url", "", "heartbeat server URL as seen from game server Pods (HEARTBEAT_URL)")
+	flag.StringVar(&heartbeatCertDir, "heartbeat-cert-dir", "",
+		"directory with tls.crt, tls.key and ca.crt; when set, heartbeats are served over TLS and game servers get the CA (HEARTBEAT_CA)")
+	flag.DurationVar(&pollInterval, "poll-interval", poller.DefaultInterval, "how often each GameServer is polled")
+	flag.IntVar(&pollWorkers, "poll-workers", poller.DefaultWorkers, "concurrent status polls")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "leader election")
 	opts := zap.Options{Development: true}
 	opts.BindFlags(flag.CommandLine)
@@ -54,22 +67,75 @@ func main() {
 		os.Exit(1)
 	}
 
-	// REGISTER BOTH CONTROLLERS HERE
+	statusPoller := &poller.Poller{Interval: pollInterval, Workers: pollWorkers}
+	if err := mgr.Add(statusPoller); err != nil {
+		setupLog.Error(err, "unable to set up status poller")
+		os.Exit(1)
+	}
+
+	var heartbeatCAFile string
+	if heartbeatCertDir != "" {
+		heartbeatCAFile = filepath.Join(heartbeatCertDir, "ca.crt")
+	}
+
+	// REGISTER ALL CONTROLLERS HERE
 	if err = (&controller.GameServerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		HeartbeatURL:    heartbeatURL,
+		HeartbeatCAFile: heartbeatCAFile,
+		Poller:          statusPoller,
+		Recorder:        mgr.GetEventRecorderFor("gameserver-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GameServer")
 		os.Exit(1)
 	}
 	if err = (&controller.GSDeploymentReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("gsdeployment-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GSDeployment")
+		os.Exit(1)
+	}
+	if err = (&controller.GameServerAllocationReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "GSDeployment")
+		setupLog.Error(err, "unable to create controller", "controller", "GameServerAllocation")
+		os.Exit(1)
+	}
+	if err = (&controller.GameServerSetReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GameServerSet")
 		os.Exit(1)
 	}
 
+	if heartbeatAddr != "0" {
+		if err := mgr.Add(&heartbeat.Server{Client: mgr.GetClient(), Addr: heartbeatAddr, CertDir: heartbeatCertDir}); err != nil {
+			setupLog.Error(err, "unable to set up heartbeat server")
+			os.Exit(1)
+		}
+	}
+
+	// Webhooks need serving certs; set ENABLE_WEBHOOKS=false for `make run`.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookgamev1alpha1.SetupGSDeploymentWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GSDeployment")
+			os.Exit(1)
+		}
+		if err = webhookgamev1alpha1.SetupGameServerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GameServer")
+			os.Exit(1)
+		}
+		if err = webhookgamev1alpha1.SetupGameServerAllocationWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GameServerAllocation")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -0,0 +1,168 @@
+// Package autoscaler asks an external service how big a fleet should be.
+//
+// The controller POSTs a Review with the fleet's current state and expects the
+// same document back with Response filled in. The result is clamped to
+// minReplicas/maxReplicas by the caller.
+package autoscaler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultTimeout bounds one webhook call; it runs inside Reconcile.
+const DefaultTimeout = 2 * time.Second
+
+// Review is the request/response envelope.
+type Review struct {
+	Request  *Request  `json:"request,omitempty"`
+	Response *Response `json:"response,omitempty"`
+}
+
+// Request describes the fleet.
+type Request struct {
+	UID               types.UID `json:"uid"`
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	Replicas          int32     `json:"replicas"`
+	ReadyReplicas     int32     `json:"readyReplicas"`
+	DrainingReplicas  int32     `json:"drainingReplicas"`
+	AllocatedReplicas int32     `json:"allocatedReplicas"`
+	MinReplicas       int32     `json:"minReplicas"`
+	MaxReplicas       int32     `json:"maxReplicas"`
+	Servers           []Server  `json:"servers"`
+}
+
+// Server is one GameServer as seen by the webhook.
+type Server struct {
+	Name       string `json:"name"`
+	State      string `json:"state,omitempty"`
+	Players    int32  `json:"players"`
+	MaxPlayers int32  `json:"maxPlayers"`
+	Draining   bool   `json:"draining,omitempty"`
+	Allocated  bool   `json:"allocated,omitempty"`
+}
+
+// Response carries the desired number of current (non-draining) servers.
+type Response struct {
+	UID      types.UID `json:"uid"`
+	Replicas int32     `json:"replicas"`
+}
+
+// Client calls scaling webhooks, reusing one HTTP client per CA bundle.
+type Client struct {
+	mu      sync.Mutex
+	clients map[[sha256.Size]byte]*http.Client
+}
+
+// Desired posts req to the webhook described by cfg and returns the desired replica count.
+func (c *Client) Desired(ctx context.Context, cfg *admissionregistrationv1.WebhookClientConfig, req Request) (int32, error) {
+	endpoint, err := Endpoint(cfg)
+	if err != nil {
+		return 0, err
+	}
+	httpc, err := c.httpClient(cfg.CABundle)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(Review{Request: &req})
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	resp, err := httpc.Do(hreq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	var out Review
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decoding webhook response: %w", err)
+	}
+	if out.Response == nil {
+		return 0, errors.New("webhook response has no response field")
+	}
+	if out.Response.UID != req.UID {
+		return 0, fmt.Errorf("webhook answered for uid %q, expected %q", out.Response.UID, req.UID)
+	}
+	if out.Response.Replicas < 0 {
+		return 0, fmt.Errorf("webhook asked for %d replicas", out.Response.Replicas)
+	}
+	return out.Response.Replicas, nil
+}
+
+// Endpoint resolves cfg to a URL; a Service becomes https://name.namespace.svc:port/path.
+func Endpoint(cfg *admissionregistrationv1.WebhookClientConfig) (string, error) {
+	switch {
+	case cfg == nil:
+		return "", errors.New("no webhook configured")
+	case cfg.URL != nil && cfg.Service != nil:
+		return "", errors.New("url and service are mutually exclusive")
+	case cfg.URL != nil:
+		u, err := url.Parse(*cfg.URL)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return "", fmt.Errorf("url %q must be an absolute http(s) URL", *cfg.URL)
+		}
+		return u.String(), nil
+	case cfg.Service != nil:
+		port := int32(443)
+		if cfg.Service.Port != nil {
+			port = *cfg.Service.Port
+		}
+		path := ""
+		if cfg.Service.Path != nil {
+			path = *cfg.Service.Path
+		}
+		return fmt.Sprintf("https://%s.%s.svc:%d%s", cfg.Service.Name, cfg.Service.Namespace, port, path), nil
+	}
+	return "", errors.New("one of url or service is required")
+}
+
+func (c *Client) httpClient(caBundle []byte) (*http.Client, error) {
+	key := sha256.Sum256(caBundle)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hc, ok := c.clients[key]; ok {
+		return hc, nil
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("caBundle contains no PEM certificates")
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	hc := &http.Client{Transport: tr, Timeout: DefaultTimeout}
+	if c.clients == nil {
+		c.clients = map[[sha256.Size]byte]*http.Client{}
+	}
+	c.clients[key] = hc
+	return hc, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/utils/ptr"
+)
+
+// answer replies to every review with the given replica count.
+func answer(replicas int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rev Review
+		Expect(json.NewDecoder(r.Body).Decode(&rev)).To(Succeed())
+		rev.Response = &Response{UID: rev.Request.UID, Replicas: replicas + rev.Request.DrainingReplicas}
+		rev.Request = nil
+		_ = json.NewEncoder(w).Encode(rev)
+	}
+}
+
+var _ = Describe("Client", func() {
+	ctx := context.Background()
+	req := Request{UID: "abc", Name: "fleet", Namespace: "games", Replicas: 3, DrainingReplicas: 1}
+
+	It("returns the webhook's replica count", func() {
+		srv := httptest.NewServer(answer(7))
+		defer srv.Close()
+
+		n, err := (&Client{}).Desired(ctx, &admissionregistrationv1.WebhookClientConfig{URL: ptr.To(srv.URL)}, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int32(8)))
+	})
+
+	It("trusts a server signed by the caBundle", func() {
+		srv := httptest.NewTLSServer(answer(4))
+		defer srv.Close()
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+		cfg := &admissionregistrationv1.WebhookClientConfig{URL: ptr.To(srv.URL)}
+		_, err := (&Client{}).Desired(ctx, cfg, req)
+		Expect(err).To(HaveOccurred()) // unknown authority
+
+		cfg.CABundle = ca
+		n, err := (&Client{}).Desired(ctx, cfg, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int32(5)))
+	})
+
+	It("rejects error statuses and answers for another request", func() {
+		bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer bad.Close()
+		_, err := (&Client{}).Desired(ctx, &admissionregistrationv1.WebhookClientConfig{URL: ptr.To(bad.URL)}, req)
+		Expect(err).To(MatchError(ContainSubstring("HTTP 502")))
+
+		wrong := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(Review{Response: &Response{UID: "other", Replicas: 1}})
+		}))
+		defer wrong.Close()
+		_, err = (&Client{}).Desired(ctx, &admissionregistrationv1.WebhookClientConfig{URL: ptr.To(wrong.URL)}, req)
+		Expect(err).To(MatchError(ContainSubstring(`expected "abc"`)))
+	})
+})
+
+var _ = Describe("Endpoint", func() {
+	It("builds an in-cluster URL for a service", func() {
+		u, err := Endpoint(&admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name: "forecaster", Namespace: "liveops", Path: ptr.To("/scale"), Port: ptr.To(int32(8443)),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u).To(Equal("https://forecaster.liveops.svc:8443/scale"))
+	})
+
+	It("requires exactly one of url and service", func() {
+		_, err := Endpoint(&admissionregistrationv1.WebhookClientConfig{})
+		Expect(err).To(HaveOccurred())
+		_, err = Endpoint(&admissionregistrationv1.WebhookClientConfig{
+			URL:     ptr.To("https://x"),
+			Service: &admissionregistrationv1.ServiceReference{Name: "a", Namespace: "b"},
+		})
+		Expect(err).To(HaveOccurred())
+		_, err = Endpoint(&admissionregistrationv1.WebhookClientConfig{URL: ptr.To("/relative")})
+		Expect(err).To(HaveOccurred())
+	})
+})
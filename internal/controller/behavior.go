@@ -0,0 +1,302 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fleetHistory is what scaling.behavior remembers about one fleet: the
+// policy's recent recommendations and the servers it recently added or
+// removed. Like the HPA's, it lives in memory; after a restart or leader
+// change the windows simply start empty.
+type fleetHistory struct {
+	recommendations []timedCount
+	changes         []timedCount // servers added (> 0) or removed (< 0)
+	wake            time.Time    // when a held-back change may go ahead
+}
+
+type timedCount struct {
+	at time.Time
+	n  int32
+}
+
+// stabilize turns a policy's recommendation for a fleet of current servers
+// into the target it may move to now. why explains a target that differs
+// from the recommendation; reason is the matching Event reason. wait is how
+// long until what holds the change back leaves its window or period, so the
+// fleet is looked at again then; 0 when nothing is held back or it never lapses.
+func (h *fleetHistory) stabilize(b *gamev1alpha1.ScalingBehavior, current, recommended int32,
+	now time.Time) (target int32, reason, why string, wait time.Duration) {
+	upWindow, downWindow := window(b.ScaleUp), window(b.ScaleDown)
+	h.recommendations = append(h.recommendations, timedCount{now, recommended})
+	h.prune(now)
+
+	// Scale up only as far as every recent recommendation agrees, and down
+	// only as far as none of them objects.
+	upRec, downRec := recommended, recommended
+	for _, rec := range h.recommendations {
+		age := now.Sub(rec.at)
+		if age <= upWindow {
+			upRec = minInt32(upRec, rec.n)
+		}
+		if age <= downWindow {
+			downRec = maxInt32(downRec, rec.n)
+		}
+	}
+	target = current
+	switch {
+	case upRec > current:
+		target = upRec
+	case downRec < current:
+		target = downRec
+	}
+	switch {
+	case recommended > current && target < recommended:
+		reason, why = "ScaleUpStabilized", fmt.Sprintf("recommendations in the last %s were as low as %d", upWindow, upRec)
+	case recommended < current && target > recommended:
+		reason, why = "ScaleDownStabilized", fmt.Sprintf("recommendations in the last %s were as high as %d", downWindow, downRec)
+	}
+
+	switch reason {
+	case "ScaleUpStabilized":
+		wait = expiry(h.recommendations, now, upWindow, func(n int32) bool { return n < recommended })
+	case "ScaleDownStabilized":
+		wait = expiry(h.recommendations, now, downWindow, func(n int32) bool { return n > recommended })
+	}
+
+	if target > current {
+		if limit, rule := h.upLimit(b.ScaleUp, current, now); target > limit {
+			target, reason, why = limit, "ScaleUpLimited", rule
+			wait = h.periodExpiry(b.ScaleUp, now, func(n int32) bool { return n > 0 })
+		}
+	} else if target < current {
+		if limit, rule := h.downLimit(b.ScaleDown, current, now); target < limit {
+			target, reason, why = limit, "ScaleDownLimited", rule
+			wait = h.periodExpiry(b.ScaleDown, now, func(n int32) bool { return n < 0 })
+		}
+	}
+	return target, reason, why, wait
+}
+
+// expiry is how long until the oldest matching entry of list leaves a span of
+// d looking back from now, plus a second so the next pass no longer sees it.
+// It is 0 when no matching entry is in the span.
+func expiry(list []timedCount, now time.Time, d time.Duration, match func(int32) bool) time.Duration {
+	for _, c := range list { // oldest first
+		if left := c.at.Add(d).Sub(now); left >= 0 && match(c.n) {
+			return left + time.Second
+		}
+	}
+	return 0
+}
+
+// periodExpiry is the earliest expiry of a matching change across the rate
+// policies' periods: the first moment any of the limits can loosen.
+func (h *fleetHistory) periodExpiry(rules *gamev1alpha1.ScalingRules, now time.Time, match func(int32) bool) time.Duration {
+	if rules == nil || rules.SelectPolicy == gamev1alpha1.SelectPolicyDisabled {
+		return 0
+	}
+	var wait time.Duration
+	for _, p := range rules.Policies {
+		if d := expiry(h.changes, now, time.Duration(p.PeriodSeconds)*time.Second, match); d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// record notes servers added (n > 0) or removed (n < 0) by the policy.
+func (h *fleetHistory) record(n int32, now time.Time) {
+	if n != 0 {
+		h.changes = append(h.changes, timedCount{now, n})
+	}
+}
+
+// upLimit is the largest fleet the scale-up policies allow right now.
+func (h *fleetHistory) upLimit(rules *gamev1alpha1.ScalingRules, current int32, now time.Time) (int32, string) {
+	if rules == nil {
+		return math.MaxInt32, ""
+	}
+	if rules.SelectPolicy == gamev1alpha1.SelectPolicyDisabled {
+		return current, "scale up is disabled"
+	}
+	if len(rules.Policies) == 0 {
+		return math.MaxInt32, ""
+	}
+	var limit int32
+	var rule string
+	for i, p := range rules.Policies {
+		// Size of the fleet when the policy's period began.
+		start := current - h.changed(now, p.PeriodSeconds, true)
+		var l int32
+		if p.Type == gamev1alpha1.ScalingRatePercent {
+			// At least one server, or a fleet at zero could never grow.
+			l = maxInt32(int32(math.Ceil(float64(start)*(1+float64(p.Value)/100))), start+1)
+		} else {
+			l = start + p.Value
+		}
+		// Max picks the policy allowing the biggest fleet, Min the smallest.
+		better := l > limit
+		if rules.SelectPolicy == gamev1alpha1.SelectPolicyMin {
+			better = l < limit
+		}
+		if i == 0 || better {
+			limit, rule = l, describeRate("adding", p)
+		}
+	}
+	return limit, rule
+}
+
+// downLimit is the smallest fleet the scale-down policies allow right now.
+func (h *fleetHistory) downLimit(rules *gamev1alpha1.ScalingRules, current int32, now time.Time) (int32, string) {
+	if rules == nil {
+		return 0, ""
+	}
+	if rules.SelectPolicy == gamev1alpha1.SelectPolicyDisabled {
+		return current, "scale down is disabled"
+	}
+	if len(rules.Policies) == 0 {
+		return 0, ""
+	}
+	var limit int32
+	var rule string
+	for i, p := range rules.Policies {
+		start := current + h.changed(now, p.PeriodSeconds, false)
+		var l int32
+		if p.Type == gamev1alpha1.ScalingRatePercent {
+			l = int32(float64(start) * (1 - float64(p.Value)/100))
+		} else {
+			l = start - p.Value
+		}
+		l = maxInt32(l, 0)
+		// Max picks the policy allowing the smallest fleet, Min the biggest.
+		better := l < limit
+		if rules.SelectPolicy == gamev1alpha1.SelectPolicyMin {
+			better = l > limit
+		}
+		if i == 0 || better {
+			limit, rule = l, describeRate("removing", p)
+		}
+	}
+	return limit, rule
+}
+
+// changed sums the servers added (up) or removed (!up) in the last period.
+func (h *fleetHistory) changed(now time.Time, periodSeconds int32, up bool) int32 {
+	var n int32
+	for _, c := range h.changes {
+		if now.Sub(c.at) > time.Duration(periodSeconds)*time.Second {
+			continue
+		}
+		if up && c.n > 0 {
+			n += c.n
+		} else if !up && c.n < 0 {
+			n -= c.n
+		}
+	}
+	return n
+}
+
+// prune drops what no window or period can look at anymore.
+func (h *fleetHistory) prune(now time.Time) {
+	const keep = time.Hour // longest window and period allowed by the API
+	drop := func(list []timedCount) []timedCount {
+		i := 0
+		for i < len(list) && now.Sub(list[i].at) > keep {
+			i++
+		}
+		return list[i:]
+	}
+	h.recommendations = drop(h.recommendations)
+	h.changes = drop(h.changes)
+}
+
+func window(rules *gamev1alpha1.ScalingRules) time.Duration {
+	if rules == nil || rules.StabilizationWindowSeconds == nil {
+		return 0
+	}
+	return time.Duration(*rules.StabilizationWindowSeconds) * time.Second
+}
+
+func describeRate(verb string, p gamev1alpha1.ScalingRatePolicy) string {
+	unit := " servers"
+	if p.Type == gamev1alpha1.ScalingRatePercent {
+		unit = "%"
+	}
+	return fmt.Sprintf("policy allows %s %d%s per %ds", verb, p.Value, unit, p.PeriodSeconds)
+}
+
+// behave applies the fleet's scaling.behavior to a recommendation for a fleet
+// with current non-draining servers, explaining any held-back change in an
+// Event. Without a behavior the recommendation is used as is.
+func (r *GSDeploymentReconciler) behave(gsd *gamev1alpha1.GSDeployment, current, recommended int32) int32 {
+	if gsd.Spec.Scaling == nil || gsd.Spec.Scaling.Behavior == nil {
+		return recommended
+	}
+	h := r.history(types.NamespacedName{Namespace: gsd.Namespace, Name: gsd.Name})
+	now := time.Now()
+	r.historyMu.Lock()
+	target, reason, why, wait := h.stabilize(gsd.Spec.Scaling.Behavior, current, recommended, now)
+	h.wake = time.Time{}
+	if wait > 0 {
+		h.wake = now.Add(wait)
+	}
+	r.historyMu.Unlock()
+	if reason != "" {
+		r.eventf(gsd, corev1.EventTypeNormal, reason, "Policy recommends %d servers, scaling to %d instead (currently %d): %s",
+			recommended, target, current, why)
+	}
+	return target
+}
+
+// behaviorRequeue is how long until a change scaling.behavior held back may
+// go ahead, or 0 if none is waiting.
+func (r *GSDeploymentReconciler) behaviorRequeue(gsd *gamev1alpha1.GSDeployment) time.Duration {
+	if gsd.Spec.Scaling == nil || gsd.Spec.Scaling.Behavior == nil {
+		return 0
+	}
+	h := r.history(types.NamespacedName{Namespace: gsd.Namespace, Name: gsd.Name})
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+	if h.wake.IsZero() {
+		return 0
+	}
+	return max(time.Until(h.wake), time.Second)
+}
+
+// recordScale remembers servers the scaling policy added or removed.
+func (r *GSDeploymentReconciler) recordScale(gsd *gamev1alpha1.GSDeployment, n int32) {
+	if n == 0 || gsd.Spec.Scaling == nil || gsd.Spec.Scaling.Behavior == nil {
+		return
+	}
+	h := r.history(types.NamespacedName{Namespace: gsd.Namespace, Name: gsd.Name})
+	r.historyMu.Lock()
+	h.record(n, time.Now())
+	r.historyMu.Unlock()
+}
+
+func (r *GSDeploymentReconciler) history(key types.NamespacedName) *fleetHistory {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+	if r.histories == nil {
+		r.histories = map[types.NamespacedName]*fleetHistory{}
+	}
+	h, ok := r.histories[key]
+	if !ok {
+		h = &fleetHistory{}
+		r.histories[key] = h
+	}
+	return h
+}
+
+func (r *GSDeploymentReconciler) forgetHistory(key types.NamespacedName) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+	delete(r.histories, key)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("fleetHistory", func() {
+	t0 := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+
+	It("scales down only to the highest recommendation in the window", func() {
+		b := &gamev1alpha1.ScalingBehavior{ScaleDown: &gamev1alpha1.ScalingRules{StabilizationWindowSeconds: ptr.To[int32](300)}}
+		h := &fleetHistory{}
+		n, _, _, _ := h.stabilize(b, 6, 6, at(0))
+		Expect(n).To(Equal(int32(6)))
+		n, _, _, _ = h.stabilize(b, 6, 4, at(60))
+		Expect(n).To(Equal(int32(6)))
+		n, reason, _, wait := h.stabilize(b, 6, 2, at(120))
+		Expect(n).To(Equal(int32(6)))
+		Expect(reason).To(Equal("ScaleDownStabilized"))
+		Expect(wait).To(Equal(181 * time.Second)) // the 6 from at(0) leaves the window
+
+		// The 6 leaves the window; the 4 still holds.
+		n, _, _, _ = h.stabilize(b, 6, 2, at(301))
+		Expect(n).To(Equal(int32(4)))
+		n, _, _, _ = h.stabilize(b, 4, 2, at(421))
+		Expect(n).To(Equal(int32(2)))
+	})
+
+	It("scales up right away without a scale-up window", func() {
+		b := &gamev1alpha1.ScalingBehavior{ScaleDown: &gamev1alpha1.ScalingRules{StabilizationWindowSeconds: ptr.To[int32](300)}}
+		h := &fleetHistory{}
+		h.stabilize(b, 6, 2, at(0))
+		n, reason, _, _ := h.stabilize(b, 6, 9, at(10))
+		Expect(n).To(Equal(int32(9)))
+		Expect(reason).To(BeEmpty())
+	})
+
+	It("limits scale down per period with the most permissive policy", func() {
+		b := &gamev1alpha1.ScalingBehavior{ScaleDown: &gamev1alpha1.ScalingRules{Policies: []gamev1alpha1.ScalingRatePolicy{
+			{Type: gamev1alpha1.ScalingRateServers, Value: 2, PeriodSeconds: 60},
+			{Type: gamev1alpha1.ScalingRatePercent, Value: 10, PeriodSeconds: 60},
+		}}}
+		h := &fleetHistory{}
+		n, reason, why, _ := h.stabilize(b, 10, 3, at(0))
+		Expect(n).To(Equal(int32(8)))
+		Expect(reason).To(Equal("ScaleDownLimited"))
+		Expect(why).To(Equal("policy allows removing 2 servers per 60s"))
+		h.record(-2, at(0))
+
+		n, _, _, wait := h.stabilize(b, 8, 3, at(30))
+		Expect(n).To(Equal(int32(8)))
+		Expect(wait).To(Equal(31 * time.Second)) // the removal at(0) leaves the period
+		n, _, _, _ = h.stabilize(b, 8, 3, at(61))
+		Expect(n).To(Equal(int32(6)))
+
+		b.ScaleDown.SelectPolicy = gamev1alpha1.SelectPolicyMin
+		n, _, why, _ = h.stabilize(b, 10, 3, at(200))
+		Expect(n).To(Equal(int32(9)))
+		Expect(why).To(ContainSubstring("10%"))
+	})
+
+	It("lets a percentage scale-up policy grow an empty fleet", func() {
+		b := &gamev1alpha1.ScalingBehavior{ScaleUp: &gamev1alpha1.ScalingRules{Policies: []gamev1alpha1.ScalingRatePolicy{
+			{Type: gamev1alpha1.ScalingRatePercent, Value: 50, PeriodSeconds: 60},
+		}}}
+		h := &fleetHistory{}
+		n, _, _, _ := h.stabilize(b, 0, 5, at(0))
+		Expect(n).To(Equal(int32(1)))
+		n, _, _, _ = h.stabilize(b, 4, 20, at(1))
+		Expect(n).To(Equal(int32(6)))
+	})
+
+	It("never scales in a disabled direction", func() {
+		b := &gamev1alpha1.ScalingBehavior{ScaleDown: &gamev1alpha1.ScalingRules{SelectPolicy: gamev1alpha1.SelectPolicyDisabled}}
+		n, reason, _, wait := (&fleetHistory{}).stabilize(b, 5, 1, at(0))
+		Expect(n).To(Equal(int32(5)))
+		Expect(reason).To(Equal("ScaleDownLimited"))
+		Expect(wait).To(BeZero())
+	})
+})
+
+var _ = Describe("scaleThreshold", func() {
+	ctx := context.Background()
+
+	idleFor := func(gs gamev1alpha1.GameServer, d time.Duration) gamev1alpha1.GameServer {
+		since := metav1.NewTime(time.Now().Add(-d))
+		gs.Status.ZeroSince = &since
+		return gs
+	}
+
+	It("removes long-idle servers within the scale-down rate and says why", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 3),
+			idleFor(fleetServer(1, gamev1alpha1.GameServerStateReady, 0), time.Hour),
+			idleFor(fleetServer(2, gamev1alpha1.GameServerStateReady, 0), time.Hour),
+			idleFor(fleetServer(3, gamev1alpha1.GameServerStateReady, 0), time.Hour),
+			idleFor(fleetServer(4, gamev1alpha1.GameServerStateReady, 0), time.Second),
+		}
+		r, _ := newScalingReconciler(servers)
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 10)
+		gsd.Spec.ScaleUpThresholdPercent, gsd.Spec.ScaleDownZeroSeconds = 80, 60
+		gsd.Spec.Scaling = &gamev1alpha1.Scaling{Behavior: &gamev1alpha1.ScalingBehavior{
+			ScaleDown: &gamev1alpha1.ScalingRules{Policies: []gamev1alpha1.ScalingRatePolicy{
+				{Type: gamev1alpha1.ScalingRateServers, Value: 1, PeriodSeconds: 60},
+			}},
+		}}
+
+		out, desired, err := r.scaleThreshold(ctx, gsd, servers, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(desired).To(Equal(int32(4)))
+		Expect(out).To(HaveLen(4))
+		Expect(recorder.Events).To(Receive(ContainSubstring("ScaleDownLimited Policy recommends 2 servers, scaling to 4 instead")))
+
+		// The period's budget is spent.
+		out, _, err = r.scaleThreshold(ctx, gsd, out, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HaveLen(4))
+	})
+
+	It("adds one server when a server is hot and nothing is idle", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 9), fleetServer(1, gamev1alpha1.GameServerStateReady, 2)}
+		r, _ := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 10)
+		gsd.Spec.ScaleUpThresholdPercent, gsd.Spec.ScaleDownZeroSeconds = 80, 60
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		pool.take(30000)
+		pool.take(30001)
+
+		out, desired, err := r.scaleThreshold(ctx, gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(desired).To(Equal(int32(3)))
+		Expect(out).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("a held-back scale change", func() {
+	ctx := context.Background()
+
+	It("comes back when the stabilization window ends", func() {
+		r, _ := newScalingReconciler(nil)
+		c := fake.NewClientBuilder().WithScheme(r.Scheme).WithStatusSubresource(&gamev1alpha1.GSDeployment{}).Build()
+		r.Client = c
+		gsd := bufferFleet(intstr.FromInt32(3), 0, 10)
+		gsd.Spec.Scaling.Behavior = &gamev1alpha1.ScalingBehavior{
+			ScaleDown: &gamev1alpha1.ScalingRules{StabilizationWindowSeconds: ptr.To[int32](120)},
+		}
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)}
+
+		// The first pass creates the buffer and recommends 3 servers.
+		res, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeZero())
+
+		// A smaller buffer recommends 1, but the 3 holds for the window.
+		Expect(c.Get(ctx, req.NamespacedName, gsd)).To(Succeed())
+		gsd.Spec.Scaling.BufferSize = ptr.To(intstr.FromInt32(1))
+		Expect(c.Update(ctx, gsd)).To(Succeed())
+		res, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(3))
+		Expect(res.RequeueAfter).To(BeNumerically("~", 121*time.Second, time.Second))
+	})
+})
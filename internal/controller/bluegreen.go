@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// blueGreenPlan is a BlueGreen fleet for one pass. While a preview is up the
+// active (blue) servers are held and keep taking allocations, and the preview
+// (green) fleet is grown to the active fleet's Ready count. Promotion only
+// changes status.blueGreen.activeRevision, which the allocator reads, so
+// allocations switch in one write; the next pass drains the old fleet. The
+// plan is nil for other strategies.
+type blueGreenPlan struct {
+	heldSet *gamev1alpha1.GameServerSet // kept serving this pass; nil when none
+	preview string                      // revision being brought up; empty when none
+	target  int32                       // preview servers wanted
+	green   int32                       // non-draining preview servers
+	status  gamev1alpha1.BlueGreenStatus
+}
+
+// planBlueGreen works out which revision is active, whether a preview is up
+// and whether it is time to promote it.
+func (r *GSDeploymentReconciler) planBlueGreen(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	current *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) (*blueGreenPlan, error) {
+	if gsd.Spec.UpdateStrategy.Type != gamev1alpha1.UpdateStrategyBlueGreen {
+		return nil, nil
+	}
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return nil, err
+	}
+	active := ""
+	if st := gsd.Status.BlueGreen; st != nil {
+		active = st.ActiveRevision
+	}
+	if active == "" {
+		active = stableRevision(gsd, sets, current, children)
+	}
+	p := &blueGreenPlan{status: gamev1alpha1.BlueGreenStatus{ActiveRevision: active}}
+	for i := range sets {
+		if sets[i].Spec.Revision == active && active != current.Spec.Revision {
+			p.heldSet = &sets[i]
+		}
+	}
+	if p.heldSet == nil {
+		// Nothing to keep serving: the current revision is (or becomes) active.
+		p.status.ActiveRevision = current.Spec.Revision
+		return p, nil
+	}
+
+	var activeReady, greenReady int32
+	for _, gs := range children {
+		if isDraining(&gs) {
+			continue
+		}
+		switch gs.Labels[gamev1alpha1.RevisionLabel] {
+		case active:
+			if isReady(&gs) {
+				activeReady++
+			}
+		case current.Spec.Revision:
+			p.green++
+			if isReady(&gs) {
+				greenReady++
+			}
+		}
+	}
+	p.preview = current.Spec.Revision
+	p.target = minInt32(maxInt32(activeReady, gsd.Spec.MinReplicas), gsd.Spec.MaxReplicas)
+	p.status.PreviewRevision = current.Spec.Revision
+	p.status.PreviewTargetReplicas, p.status.PreviewReadyReplicas = p.target, greenReady
+	if greenReady < p.target {
+		return p, nil
+	}
+
+	auto := gsd.Spec.UpdateStrategy.BlueGreen == nil || gsd.Spec.UpdateStrategy.BlueGreen.AutoPromotionEnabled == nil ||
+		*gsd.Spec.UpdateStrategy.BlueGreen.AutoPromotionEnabled
+	if !auto {
+		if gsd.Annotations[promoteAnno] != "true" {
+			p.status.AwaitingPromotion = true
+			return p, nil
+		}
+		if err := r.clearPromotion(ctx, gsd); err != nil {
+			return nil, err
+		}
+	}
+	// The old fleet stays held this pass; it drains once the switch is written.
+	p.status = gamev1alpha1.BlueGreenStatus{ActiveRevision: current.Spec.Revision}
+	r.eventf(gsd, corev1.EventTypeNormal, "BlueGreenPromoted",
+		"Switched allocations from revision %s to %s (%d Ready servers)", active, current.Spec.Revision, greenReady)
+	return p, nil
+}
+
+// holds reports whether gs is an active server kept serving next to the preview.
+func (p *blueGreenPlan) holds(gs *gamev1alpha1.GameServer) bool {
+	return p != nil && p.heldSet != nil && !isDraining(gs) &&
+		gs.Labels[gamev1alpha1.RevisionLabel] == p.heldSet.Spec.Revision
+}
+
+// surges reports whether the rollout may surge replacements for outdated
+// servers; a BlueGreen fleet brings up its whole preview instead.
+func (p *blueGreenPlan) surges() bool {
+	return p == nil
+}
+
+// missing is how many preview servers are still to be created.
+func (p *blueGreenPlan) missing() int32 {
+	if p == nil || p.preview == "" {
+		return 0
+	}
+	return maxInt32(p.target-p.green, 0)
+}
+
+// growSet is the set new servers go to when the fleet grows: the active one
+// while a preview is up.
+func (p *blueGreenPlan) growSet(current *gamev1alpha1.GameServerSet) *gamev1alpha1.GameServerSet {
+	if p == nil || p.heldSet == nil {
+		return current
+	}
+	return p.heldSet
+}
+
+// hide splits the preview servers off children so the scaling policies only
+// size the active fleet.
+func (p *blueGreenPlan) hide(children []gamev1alpha1.GameServer) (shown, hidden []gamev1alpha1.GameServer) {
+	if p == nil || p.preview == "" {
+		return children, nil
+	}
+	for _, gs := range children {
+		if gs.Labels[gamev1alpha1.RevisionLabel] == p.preview {
+			hidden = append(hidden, gs)
+		} else {
+			shown = append(shown, gs)
+		}
+	}
+	return shown, hidden
+}
+
+// activeRevision is the revision a BlueGreen fleet allocates from; empty
+// when every revision may be allocated.
+func activeRevision(gsd *gamev1alpha1.GSDeployment) string {
+	if gsd.Spec.UpdateStrategy.Type != gamev1alpha1.UpdateStrategyBlueGreen || gsd.Status.BlueGreen == nil {
+		return ""
+	}
+	return gsd.Status.BlueGreen.ActiveRevision
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("BlueGreen", func() {
+	ctx := context.Background()
+
+	var (
+		gsd         *gamev1alpha1.GSDeployment
+		r           *GSDeploymentReconciler
+		c           client.Client
+		blue, green *gamev1alpha1.GameServerSet
+	)
+	// servers returns n blue and m green servers, all Ready and empty.
+	servers := func(n, m int) []gamev1alpha1.GameServer {
+		var out []gamev1alpha1.GameServer
+		for i := 0; i < n+m; i++ {
+			gs := fleetServer(i, gamev1alpha1.GameServerStateReady, 0)
+			gs.Labels[gamev1alpha1.RevisionLabel] = blue.Spec.Revision
+			if i >= n {
+				gs.Labels[gamev1alpha1.RevisionLabel] = green.Spec.Revision
+			}
+			out = append(out, gs)
+		}
+		return out
+	}
+	BeforeEach(func() {
+		gsd = bufferFleet(intstr.FromInt32(1), 0, 20)
+		gsd.Spec.Image = "game:v1"
+		gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{Type: gamev1alpha1.UpdateStrategyBlueGreen}
+		r, c = newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		var err error
+		blue, err = r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Spec.Image = "game:v2"
+		green, err = r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Status.BlueGreen = &gamev1alpha1.BlueGreenStatus{ActiveRevision: blue.Spec.Revision}
+	})
+
+	It("brings up a preview sized to the active fleet while blue keeps serving", func() {
+		fleet := servers(4, 1)
+		fleet[2].Status.State = gamev1alpha1.GameServerStateScheduled
+		p, err := r.planBlueGreen(ctx, gsd, green, fleet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.ActiveRevision).To(Equal(blue.Spec.Revision))
+		Expect(p.status.PreviewRevision).To(Equal(green.Spec.Revision))
+		Expect(p.status.PreviewTargetReplicas).To(Equal(int32(3)))
+		Expect(p.missing()).To(Equal(int32(2)))
+		Expect(p.holds(&fleet[0])).To(BeTrue())
+		Expect(p.holds(&fleet[4])).To(BeFalse())
+		Expect(p.surges()).To(BeFalse())
+		Expect(p.growSet(green).Name).To(Equal(blue.Name))
+
+		shown, hidden := p.hide(fleet)
+		Expect(shown).To(HaveLen(4))
+		Expect(hidden).To(ConsistOf(HaveField("Name", fleet[4].Name)))
+		Expect(activeRevision(gsd)).To(Equal(blue.Spec.Revision))
+		Expect(onRevision(fleet, blue.Spec.Revision)).To(HaveLen(4))
+	})
+
+	It("switches allocations once the preview is Ready", func() {
+		p, err := r.planBlueGreen(ctx, gsd, green, servers(3, 3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.ActiveRevision).To(Equal(green.Spec.Revision))
+		Expect(p.status.PreviewRevision).To(BeEmpty())
+		// Blue is still held in the pass that writes the switch.
+		Expect(p.holds(&servers(3, 3)[0])).To(BeTrue())
+
+		gsd.Status.BlueGreen = &p.status
+		p, err = r.planBlueGreen(ctx, gsd, green, servers(3, 3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.holds(&servers(3, 3)[0])).To(BeFalse())
+		Expect(p.missing()).To(BeZero())
+	})
+
+	It("waits for promotion when autoPromotionEnabled is false", func() {
+		gsd.Spec.UpdateStrategy.BlueGreen = &gamev1alpha1.BlueGreenStrategy{AutoPromotionEnabled: ptr.To(false)}
+		p, err := r.planBlueGreen(ctx, gsd, green, servers(3, 3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.ActiveRevision).To(Equal(blue.Spec.Revision))
+		Expect(p.status.AwaitingPromotion).To(BeTrue())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), gsd)).To(Succeed())
+		gsd.Annotations = map[string]string{promoteAnno: "true"}
+		gsd.Spec.UpdateStrategy.BlueGreen = &gamev1alpha1.BlueGreenStrategy{AutoPromotionEnabled: ptr.To(false)}
+		Expect(c.Update(ctx, gsd)).To(Succeed())
+		p, err = r.planBlueGreen(ctx, gsd, green, servers(3, 3))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.ActiveRevision).To(Equal(green.Spec.Revision))
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(promoteAnno))
+	})
+})
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// promoteAnno on a GSDeployment releases a Canary pause that has no duration.
+// The controller removes it once the pause is passed.
+const promoteAnno = "game.example.com/promote"
+
+// canaryPlan splits a Canary fleet between the stable and the current
+// revision for one pass. Stable servers keep serving; as many of them as the
+// canary is short of the step's weight are drained, and the surge only
+// creates the canaries still wanted. stable is nil when no canary is in
+// progress; the plan itself is nil for other strategies.
+type canaryPlan struct {
+	stable   *gamev1alpha1.GameServerSet
+	target   int32 // canary servers the current weight asks for
+	canary   int32 // non-draining servers of the current revision
+	draining int32 // stable servers already draining toward target
+	status   gamev1alpha1.CanaryStatus
+	requeue  time.Duration // until a timed pause ends
+}
+
+// planCanary works out where the fleet's Canary rollout stands: which steps
+// are done, the weight now in force and the split it asks for.
+func (r *GSDeploymentReconciler) planCanary(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	current *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) (*canaryPlan, error) {
+	if gsd.Spec.UpdateStrategy.Type != gamev1alpha1.UpdateStrategyCanary {
+		return nil, nil
+	}
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return nil, err
+	}
+	p := &canaryPlan{}
+	stableRev := stableRevision(gsd, sets, current, children)
+	if stableRev != current.Spec.Revision {
+		for i := range sets {
+			if sets[i].Spec.Revision == stableRev {
+				p.stable = &sets[i]
+			}
+		}
+	}
+
+	var stable, canaryReady int32
+	for _, gs := range children {
+		switch rev := gs.Labels[gamev1alpha1.RevisionLabel]; {
+		case rev == current.Spec.Revision && !isDraining(&gs):
+			p.canary++
+			if isReady(&gs) {
+				canaryReady++
+			}
+		case p.stable == nil || rev != stableRev:
+		case isDraining(&gs):
+			p.draining++
+		default:
+			stable++
+		}
+	}
+
+	if p.stable == nil {
+		// The fleet runs the current revision; keep reporting the finished rollout.
+		if st := gsd.Status.Canary; st != nil && st.Revision == current.Spec.Revision {
+			p.status = *st.DeepCopy()
+			p.status.CanaryReplicas, p.status.StableReplicas = p.canary, 0
+			p.status.PauseStartTime, p.status.AwaitingPromotion = nil, false
+			return p, nil
+		}
+		return nil, nil
+	}
+
+	// A newer revision starts over at the first step.
+	st := gamev1alpha1.CanaryStatus{Revision: current.Spec.Revision}
+	if prev := gsd.Status.Canary; prev != nil && prev.Revision == current.Spec.Revision {
+		st = *prev.DeepCopy()
+	}
+	var steps []gamev1alpha1.CanaryStep
+	if gsd.Spec.UpdateStrategy.Canary != nil {
+		steps = gsd.Spec.UpdateStrategy.Canary.Steps
+	}
+	fleet := stable + p.canary
+	weight := int32(0)
+	for _, step := range steps[:min(int(st.CurrentStepIndex), len(steps))] {
+		if step.SetWeight != nil {
+			weight = *step.SetWeight
+		}
+	}
+	now := time.Now()
+	promoted := gsd.Annotations[promoteAnno] == "true"
+	startIndex := st.CurrentStepIndex
+	st.AwaitingPromotion = false
+	for ; int(st.CurrentStepIndex) < len(steps); st.CurrentStepIndex++ {
+		step := steps[st.CurrentStepIndex]
+		if step.SetWeight != nil {
+			weight = *step.SetWeight
+			if canaryReady < canaryTarget(fleet, weight) {
+				break
+			}
+			continue
+		}
+		if step.Pause == nil {
+			continue
+		}
+		if st.PauseStartTime == nil {
+			st.PauseStartTime = &metav1.Time{Time: now}
+		}
+		if d := step.Pause.DurationSeconds; d != nil {
+			end := st.PauseStartTime.Add(time.Duration(*d) * time.Second)
+			if now.Before(end) {
+				p.requeue = end.Sub(now)
+				break
+			}
+		} else {
+			if !promoted {
+				st.AwaitingPromotion = true
+				break
+			}
+			promoted = false
+			if err := r.clearPromotion(ctx, gsd); err != nil {
+				return nil, err
+			}
+			r.eventf(gsd, corev1.EventTypeNormal, "CanaryPromoted", "Promoted past pause at step %d", st.CurrentStepIndex)
+		}
+		st.PauseStartTime = nil
+	}
+	if int(st.CurrentStepIndex) >= len(steps) {
+		st.CurrentStepIndex = int32(len(steps))
+		weight = 100
+	}
+	if st.CurrentStepIndex != startIndex {
+		r.eventf(gsd, corev1.EventTypeNormal, "CanaryStep", "Canary at step %d of %d, weight %d%%",
+			st.CurrentStepIndex, len(steps), weight)
+	}
+
+	p.target = canaryTarget(fleet, weight)
+	st.Weight = weight
+	st.CanaryReplicas, st.StableReplicas = p.canary, stable
+	p.status = st
+	return p, nil
+}
+
+// canaryTarget is weight percent of fleet, rounded up so any weight above
+// zero puts at least one server on the new revision.
+func canaryTarget(fleet, weight int32) int32 {
+	return (fleet*weight + 99) / 100
+}
+
+// stableRevision is the revision the whole fleet last ran. Before the first
+// recorded one it is the newest other revision still running, if any.
+func stableRevision(gsd *gamev1alpha1.GSDeployment, sets []gamev1alpha1.GameServerSet,
+	current *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) string {
+	if gsd.Status.CurrentRevision != "" {
+		return gsd.Status.CurrentRevision
+	}
+	running := map[string]bool{}
+	for _, gs := range children {
+		running[gs.Labels[gamev1alpha1.RevisionLabel]] = true
+	}
+	for _, set := range sets {
+		if set.Name != current.Name && running[set.Spec.Revision] {
+			return set.Spec.Revision
+		}
+	}
+	return current.Spec.Revision
+}
+
+// holds reports whether gs is a stable server the canary keeps serving.
+func (p *canaryPlan) holds(gs *gamev1alpha1.GameServer) bool {
+	return p != nil && p.stable != nil && !isDraining(gs) &&
+		gs.Labels[gamev1alpha1.RevisionLabel] == p.stable.Spec.Revision
+}
+
+// shift picks the stable servers to drain so the canary can grow to its
+// target: as many as it is short, beyond those already draining. Servers no
+// match holds go first, then the emptiest, then the oldest.
+func (p *canaryPlan) shift(stable []gamev1alpha1.GameServer) []gamev1alpha1.GameServer {
+	if p == nil || p.stable == nil {
+		return nil
+	}
+	short := int(p.target - p.canary - p.draining)
+	if short <= 0 {
+		return nil
+	}
+	sort.SliceStable(stable, func(i, j int) bool {
+		bi := isAllocated(&stable[i]) || isReserved(&stable[i])
+		bj := isAllocated(&stable[j]) || isReserved(&stable[j])
+		if bi != bj {
+			return !bi
+		}
+		if stable[i].Status.Players != stable[j].Status.Players {
+			return stable[i].Status.Players < stable[j].Status.Players
+		}
+		return stable[i].CreationTimestamp.Before(&stable[j].CreationTimestamp)
+	})
+	drain := stable[:min(short, len(stable))]
+	p.status.StableReplicas -= int32(len(drain))
+	return drain
+}
+
+// wantsMore reports whether new servers should be canaries; without a canary
+// in progress every new server is current.
+func (p *canaryPlan) wantsMore() bool {
+	return p == nil || p.stable == nil || p.canary < p.target
+}
+
+// added counts a canary created this pass.
+func (p *canaryPlan) added() {
+	if p != nil {
+		p.canary++
+		p.status.CanaryReplicas++
+	}
+}
+
+// growSet is the set new servers go to when the fleet grows: the canary until
+// it has its share, the stable revision after that.
+func (p *canaryPlan) growSet(current *gamev1alpha1.GameServerSet) *gamev1alpha1.GameServerSet {
+	if p.wantsMore() {
+		return current
+	}
+	return p.stable
+}
+
+// clearPromotion removes promoteAnno, touching nothing else on the object.
+func (r *GSDeploymentReconciler) clearPromotion(ctx context.Context, gsd *gamev1alpha1.GSDeployment) error {
+	obj := &gamev1alpha1.GSDeployment{ObjectMeta: metav1.ObjectMeta{Name: gsd.Name, Namespace: gsd.Namespace}}
+	patch := []byte(`{"metadata":{"annotations":{"` + promoteAnno + `":null}}}`)
+	if err := r.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	delete(gsd.Annotations, promoteAnno)
+	gsd.ResourceVersion = obj.ResourceVersion
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("Canary", func() {
+	ctx := context.Background()
+
+	var (
+		gsd            *gamev1alpha1.GSDeployment
+		r              *GSDeploymentReconciler
+		c              client.Client
+		stable, canary *gamev1alpha1.GameServerSet
+	)
+	// servers returns n stable and m canary servers, all Ready and empty.
+	servers := func(n, m int) []gamev1alpha1.GameServer {
+		var out []gamev1alpha1.GameServer
+		for i := 0; i < n+m; i++ {
+			gs := fleetServer(i, gamev1alpha1.GameServerStateReady, 0)
+			gs.Labels[gamev1alpha1.RevisionLabel] = stable.Spec.Revision
+			if i >= n {
+				gs.Labels[gamev1alpha1.RevisionLabel] = canary.Spec.Revision
+			}
+			out = append(out, gs)
+		}
+		return out
+	}
+	BeforeEach(func() {
+		gsd = bufferFleet(intstr.FromInt32(1), 0, 20)
+		gsd.Spec.Image = "game:v1"
+		gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{
+			Type: gamev1alpha1.UpdateStrategyCanary,
+			Canary: &gamev1alpha1.CanaryStrategy{Steps: []gamev1alpha1.CanaryStep{
+				{SetWeight: ptr.To[int32](10)},
+				{Pause: &gamev1alpha1.CanaryPause{}},
+				{SetWeight: ptr.To[int32](50)},
+				{Pause: &gamev1alpha1.CanaryPause{DurationSeconds: ptr.To[int32](600)}},
+			}},
+		}
+		r, c = newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		var err error
+		stable, err = r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Spec.Image = "game:v2"
+		canary, err = r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Status.CurrentRevision = stable.Spec.Revision
+	})
+
+	It("drains only the stable servers the first weight needs", func() {
+		fleet := servers(10, 0)
+		fleet[3].Status.Players = 4
+		p, err := r.planCanary(ctx, gsd, canary, fleet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.CurrentStepIndex).To(BeZero())
+		Expect(p.status.Weight).To(Equal(int32(10)))
+		Expect(p.target).To(Equal(int32(1)))
+
+		var held []gamev1alpha1.GameServer
+		for i := range fleet {
+			if p.holds(&fleet[i]) {
+				held = append(held, fleet[i])
+			}
+		}
+		Expect(held).To(HaveLen(10))
+		drain := p.shift(held)
+		Expect(drain).To(HaveLen(1))
+		Expect(drain[0].Status.Players).To(BeZero())
+		Expect(p.wantsMore()).To(BeTrue())
+		Expect(p.growSet(canary).Name).To(Equal(canary.Name))
+		p.added()
+		Expect(p.wantsMore()).To(BeFalse())
+		Expect(p.growSet(canary).Name).To(Equal(stable.Name))
+	})
+
+	It("waits at an untimed pause until promoted", func() {
+		p, err := r.planCanary(ctx, gsd, canary, servers(9, 1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.CurrentStepIndex).To(Equal(int32(1)))
+		Expect(p.status.AwaitingPromotion).To(BeTrue())
+		Expect(p.status.CanaryReplicas).To(Equal(int32(1)))
+		Expect(p.status.StableReplicas).To(Equal(int32(9)))
+		gsd.Status.Canary = &p.status
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), gsd)).To(Succeed())
+		gsd.Annotations = map[string]string{promoteAnno: "true"}
+		Expect(c.Update(ctx, gsd)).To(Succeed())
+		p, err = r.planCanary(ctx, gsd, canary, servers(9, 1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.CurrentStepIndex).To(Equal(int32(2)))
+		Expect(p.status.Weight).To(Equal(int32(50)))
+		Expect(p.status.AwaitingPromotion).To(BeFalse())
+		Expect(p.target).To(Equal(int32(5)))
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(promoteAnno))
+	})
+
+	It("holds a timed pause until it has elapsed, then finishes on the whole fleet", func() {
+		gsd.Status.Canary = &gamev1alpha1.CanaryStatus{Revision: canary.Spec.Revision, CurrentStepIndex: 3,
+			PauseStartTime: &metav1.Time{Time: time.Now().Add(-5 * time.Minute)}}
+		p, err := r.planCanary(ctx, gsd, canary, servers(5, 5))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.CurrentStepIndex).To(Equal(int32(3)))
+		Expect(p.requeue).To(BeNumerically("~", 5*time.Minute, time.Second))
+
+		gsd.Status.Canary.PauseStartTime = &metav1.Time{Time: time.Now().Add(-11 * time.Minute)}
+		p, err = r.planCanary(ctx, gsd, canary, servers(5, 5))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.CurrentStepIndex).To(Equal(int32(4)))
+		Expect(p.status.Weight).To(Equal(int32(100)))
+		Expect(p.target).To(Equal(int32(10)))
+	})
+
+	It("starts over when a newer revision replaces the canary", func() {
+		gsd.Status.Canary = &gamev1alpha1.CanaryStatus{Revision: "older", CurrentStepIndex: 3}
+		p, err := r.planCanary(ctx, gsd, canary, servers(10, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.status.Revision).To(Equal(canary.Spec.Revision))
+		Expect(p.status.CurrentStepIndex).To(BeZero())
+	})
+
+	It("is not in progress once the fleet runs the current revision", func() {
+		gsd.Status.CurrentRevision = canary.Spec.Revision
+		p, err := r.planCanary(ctx, gsd, canary, servers(0, 4))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+		Expect(p.wantsMore()).To(BeTrue())
+		Expect(p.growSet(canary).Name).To(Equal(canary.Name))
+	})
+})
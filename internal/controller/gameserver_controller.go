@@ -2,12 +2,15 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/heartbeat"
+	"github.com/ahbeigi/gameserver-operator/internal/poller"
+	"github.com/ahbeigi/gameserver-operator/internal/probe"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -16,21 +19,36 @@ import (
 	"k8s.io/apimachinery/pkg/runtime" // << add this
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 //+kubebuilder:rbac:groups=game.example.com,resources=gameservers,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=game.example.com,resources=gameservers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=game.example.com,resources=gameservers/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=pods;events,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=create;update
 
 type GameServerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
-	httpc  *http.Client
+	// Base URL of the heartbeat server as seen from game server Pods; when set,
+	// Push/Both servers get HEARTBEAT_URL pointing at their own endpoint.
+	HeartbeatURL string
+	// HeartbeatCAFile, if set, is the PEM CA of a TLS heartbeat server. It is
+	// read for every new Pod, so renewals reach the Pods created after them.
+	HeartbeatCAFile string
+	// Poller probes Running servers off the reconcile path; nil disables polling.
+	Poller *poller.Poller
+	// Recorder records state changes, Pod creations and failed polls; nil
+	// records nothing.
+	Recorder record.EventRecorder
 }
 
 func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -38,153 +56,491 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	var gs gamev1alpha1.GameServer
 	if err := r.Get(ctx, req.NamespacedName, &gs); err != nil {
+		if kerrors.IsNotFound(err) {
+			r.Poller.Untrack(req.NamespacedName)
+			forgetGameServerMetrics(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !gs.DeletionTimestamp.IsZero() {
+		r.Poller.Untrack(req.NamespacedName)
+		return ctrl.Result{}, r.updateState(ctx, &gs, gamev1alpha1.GameServerStateShutdown)
+	}
+
 	// 1) Ensure Pod exists (1:1)
 	var pod corev1.Pod
 	err := r.Get(ctx, types.NamespacedName{Name: gs.Name, Namespace: gs.Namespace}, &pod)
 	if kerrors.IsNotFound(err) {
-		pollPath := gs.Spec.PollPath
-		if pollPath == "" {
-			pollPath = "/status"
-		}
-		pod = corev1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      gs.Name,
-				Namespace: gs.Namespace,
-				Labels:    map[string]string{"app": gs.Name, "game.example.com/owner": gs.Name},
-			},
-			Spec: corev1.PodSpec{
-				HostNetwork:  true,
-				DNSPolicy:    corev1.DNSClusterFirstWithHostNet,
-				NodeSelector: gs.Spec.NodeSelector,
-				Containers: []corev1.Container{{
-					Name:  "server",
-					Image: defaultIfEmpty(gs.Spec.Image, "kyon/gameserver:latest"),
-					Env: append(gs.Spec.Env, corev1.EnvVar{
-						Name:  "GAME_PORT",
-						Value: fmt.Sprint(gs.Spec.Port),
-					}),
-					Ports:     []corev1.ContainerPort{{ContainerPort: gs.Spec.Port}},
-					Resources: gs.Spec.Resources,
-					ReadinessProbe: &corev1.Probe{
-						ProbeHandler: corev1.ProbeHandler{
-							HTTPGet: &corev1.HTTPGetAction{
-								Path: pollPath,
-								Port: intstr.FromInt(int(gs.Spec.Port)),
-							},
-						},
-						InitialDelaySeconds: 2,
-						PeriodSeconds:       5,
-						TimeoutSeconds:      2,
-					},
-				}},
-				RestartPolicy: corev1.RestartPolicyAlways,
-			},
+		if pod, err = buildPod(&gs); err != nil {
+			// Only a spec change can fix the template; don't retry until then.
+			r.eventf(&gs, corev1.EventTypeWarning, "InvalidTemplate", "Cannot create Pod: %v", err)
+			return ctrl.Result{}, reconcile.TerminalError(err)
+		}
+		old := gs.Status.DeepCopy()
+		// A Pod that vanished under a running server is a health event; the
+		// replacement then starts over.
+		if gs.Status.State != "" && gs.Status.State != gamev1alpha1.GameServerStateCreating {
+			setState(ctx, &gs, gamev1alpha1.GameServerStateUnhealthy)
+		} else {
+			setState(ctx, &gs, gamev1alpha1.GameServerStateCreating)
+		}
+		if heartbeat.Enabled(&gs) {
+			if err := r.addHeartbeatCredentials(ctx, &pod, &gs); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 		_ = ctrl.SetControllerReference(&gs, &pod, r.Scheme)
 		if err := r.Create(ctx, &pod); err != nil {
 			log.Error(err, "creating Pod")
 			return ctrl.Result{}, err
 		}
+		r.eventf(&gs, corev1.EventTypeNormal, "PodCreated", "Created Pod %s for port %d", pod.Name, gs.Spec.Port)
+		r.Poller.Untrack(req.NamespacedName)
+		setState(ctx, &gs, gamev1alpha1.GameServerStateStarting)
+		return ctrl.Result{}, client.IgnoreNotFound(r.writeStatus(ctx, &gs, old))
 	} else if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// 2) Phase from Pod
-	phase := "Pending"
-	switch pod.Status.Phase {
-	case corev1.PodRunning:
-		phase = "Running"
-	case corev1.PodFailed:
-		phase = "Error"
-	case corev1.PodSucceeded:
-		phase = "Terminating"
-	case corev1.PodPending:
-		phase = "Pending"
+	// 2) State from the Pod until it runs
+	switch {
+	case !pod.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded:
+		r.Poller.Untrack(req.NamespacedName)
+		return ctrl.Result{}, r.updateState(ctx, &gs, gamev1alpha1.GameServerStateShutdown)
+	case pod.Status.Phase == corev1.PodFailed:
+		r.Poller.Untrack(req.NamespacedName)
+		return ctrl.Result{}, r.updateState(ctx, &gs, gamev1alpha1.GameServerStateUnhealthy)
+	case pod.Status.Phase != corev1.PodRunning:
+		r.Poller.Untrack(req.NamespacedName)
+		next := gamev1alpha1.GameServerStateStarting
+		if pod.Spec.NodeName != "" {
+			next = gamev1alpha1.GameServerStateScheduled
+		}
+		return ctrl.Result{}, r.updateState(ctx, &gs, next)
 	}
 
-	// 3) Poll /status if Running
-	now := metav1.Now()
+	// 3) Running: heartbeats or the poller's latest observation decide
+	if gs.Spec.StatusMode == gamev1alpha1.StatusModePush {
+		r.Poller.Untrack(req.NamespacedName)
+		return r.checkHeartbeat(ctx, &gs, &pod, metav1.Now())
+	}
+	if pod.Status.HostIP == "" {
+		return ctrl.Result{}, nil
+	}
+	port := gs.Spec.Port
+	if gs.Spec.StatusProbe != nil && gs.Spec.StatusProbe.Port != nil {
+		port = *gs.Spec.StatusProbe.Port
+	}
+	r.Poller.Track(req.NamespacedName, poller.Target{
+		Host:     pod.Status.HostIP,
+		Port:     port,
+		Probe:    gs.Spec.StatusProbe,
+		PollPath: gs.Spec.PollPath,
+	})
+	obs, ok := r.Poller.Last(req.NamespacedName)
+	if !ok {
+		// Not polled yet; the poller's event brings us back.
+		return ctrl.Result{}, r.updateState(ctx, &gs, gamev1alpha1.GameServerStateRequestReady)
+	}
+	old := gs.Status.DeepCopy()
+	applyObservation(&gs, &pod, obs)
+	lapse := applyReservation(&gs, time.Now())
+	if err := r.writeStatus(ctx, &gs, old); err != nil {
+		return ctrl.Result{}, err
+	}
+	if obs.Err != nil && (old.LastPolled == nil || !old.LastPolled.Equal(gs.Status.LastPolled)) {
+		r.eventf(&gs, corev1.EventTypeWarning, "PollFailed", "Status poll of %s:%d failed: %v",
+			pod.Status.HostIP, port, obs.Err)
+	}
+	if obs.Err == nil && obs.Result.HasCounts {
+		gsPlayers.WithLabelValues(gs.Namespace, gs.Name).Set(float64(obs.Result.Players))
+		gsMaxPlayers.WithLabelValues(gs.Namespace, gs.Name).Set(float64(obs.Result.MaxPlayers))
+	}
+	return ctrl.Result{RequeueAfter: lapse}, nil
+}
+
+// applyObservation folds a poll result into the status. Timestamps come from
+// the observation, so applying the same one twice changes nothing.
+func applyObservation(gs *gamev1alpha1.GameServer, pod *corev1.Pod, obs poller.Observation) {
+	at := metav1.NewTime(obs.At)
 	reach := metav1.Condition{
 		Type:               "Reachable",
-		Status:             metav1.ConditionFalse,
-		Reason:             "NotReady",
-		LastTransitionTime: now,
+		LastTransitionTime: at,
 		ObservedGeneration: gs.Generation,
 	}
-	if pod.Status.Phase == corev1.PodRunning && pod.Status.HostIP != "" {
-		pollPath := defaultIfEmpty(gs.Spec.PollPath, "/status")
-		endpoint := fmt.Sprintf("http://%s:%d%s", pod.Status.HostIP, gs.Spec.Port, pollPath)
-		if r.httpc == nil {
-			r.httpc = &http.Client{Timeout: 2 * time.Second}
-		}
-
-		reqCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-		defer cancel()
-		req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, endpoint, nil)
-		resp, err := r.httpc.Do(req)
-		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			var body struct {
-				Players    int32 `json:"players"`
-				MaxPlayers int32 `json:"maxPlayers"`
-			}
-			if json.NewDecoder(resp.Body).Decode(&body) == nil {
-				old := gs.DeepCopy()
-				gs.Status.Endpoint = endpoint
-				gs.Status.LastPolled = &now
-				gs.Status.Players = body.Players
-				gs.Status.MaxPlayers = body.MaxPlayers
-				gs.Status.NodeName = pod.Spec.NodeName
-				gs.Status.Phase = phase
-				if body.Players == 0 {
-					if gs.Status.ZeroSince == nil {
-						gs.Status.ZeroSince = &now
-					}
-				} else {
-					gs.Status.ZeroSince = nil
-				}
-				reach.Status = metav1.ConditionTrue
-				reach.Reason = "OK"
-				reach.Message = "Status polled"
-				setOrUpdateCondition(&gs.Status.Conditions, reach)
-				if !equality.Semantic.DeepEqual(old.Status, gs.Status) {
-					if err := r.Status().Update(ctx, &gs); err != nil {
-						return ctrl.Result{}, err
-					}
-				}
-			}
-			if resp.Body != nil {
-				resp.Body.Close()
+	gs.Status.LastPolled = &at
+	if obs.Err != nil {
+		gs.Status.SetState(gamev1alpha1.GameServerStateUnhealthy)
+		reach.Status = metav1.ConditionFalse
+		reach.Reason = pollFailureConditionReason(probe.Reason(obs.Err))
+		reach.Message = obs.Err.Error()
+		setOrUpdateCondition(&gs.Status.Conditions, reach)
+		return
+	}
+
+	gs.Status.Endpoint = obs.Endpoint
+	gs.Status.NodeName = pod.Spec.NodeName
+	gs.Status.SetState(servingState(gs))
+	// Liveness-only probes (TCP) leave the counts alone and mark them unknown
+	// so the server is never mistaken for idle. With Both, pushes supply them.
+	if res := obs.Result; res.HasCounts {
+		gs.Status.PlayersUnknown = false
+		gs.Status.Players = res.Players
+		gs.Status.MaxPlayers = res.MaxPlayers
+		if res.Players == 0 {
+			if gs.Status.ZeroSince == nil {
+				gs.Status.ZeroSince = &at
 			}
 		} else {
-			gs.Status.Phase = "Unreachable"
-			reach.Status = metav1.ConditionFalse
-			reach.Reason = "ConnectionError"
-			if err != nil {
-				reach.Message = err.Error()
-			} else {
-				reach.Message = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			gs.Status.ZeroSince = nil
+		}
+	} else if gs.Spec.StatusMode != gamev1alpha1.StatusModeBoth {
+		gs.Status.PlayersUnknown = true
+		gs.Status.ZeroSince = nil
+	}
+	reach.Status = metav1.ConditionTrue
+	reach.Reason = "OK"
+	reach.Message = "Status polled"
+	setOrUpdateCondition(&gs.Status.Conditions, reach)
+}
+
+// servingState is the state of a healthy server that reports in: Allocated
+// while a GameServerAllocation holds it, Ready otherwise.
+func servingState(gs *gamev1alpha1.GameServer) gamev1alpha1.GameServerState {
+	if gs.GetAnnotations()[allocatedAnno] != "" {
+		return gamev1alpha1.GameServerStateAllocated
+	}
+	return gamev1alpha1.GameServerStateReady
+}
+
+// applyReservation mirrors the reservation annotations into the status and
+// moves a serving server between Ready and Reserved. An allocation overrides
+// a reservation. It returns how long until the reservation lapses, or 0.
+func applyReservation(gs *gamev1alpha1.GameServer, now time.Time) time.Duration {
+	by, until, ok := reservation(gs, now)
+	if !ok || isAllocated(gs) {
+		gs.Status.ReservedBy, gs.Status.ReservedUntil = "", nil
+		if gs.Status.State == gamev1alpha1.GameServerStateReserved {
+			gs.Status.SetState(gamev1alpha1.GameServerStateReady)
+		}
+		return 0
+	}
+	t := metav1.NewTime(until)
+	gs.Status.ReservedBy, gs.Status.ReservedUntil = by, &t
+	if gs.Status.State == gamev1alpha1.GameServerStateReady {
+		gs.Status.SetState(gamev1alpha1.GameServerStateReserved)
+	}
+	return until.Sub(now)
+}
+
+// setState moves gs to next and logs a transition the state machine forbids.
+func setState(ctx context.Context, gs *gamev1alpha1.GameServer, next gamev1alpha1.GameServerState) bool {
+	from := gs.Status.State
+	if gs.Status.SetState(next) {
+		return true
+	}
+	ctrllog.FromContext(ctx).Info("ignoring disallowed state transition", "from", from, "to", next)
+	return false
+}
+
+// updateState moves gs to next and writes the status if anything changed.
+func (r *GameServerReconciler) updateState(ctx context.Context, gs *gamev1alpha1.GameServer, next gamev1alpha1.GameServerState) error {
+	old := gs.Status.DeepCopy()
+	setState(ctx, gs, next)
+	return client.IgnoreNotFound(r.writeStatus(ctx, gs, old))
+}
+
+// writeStatus writes the status if it differs from old and records a
+// PhaseChanged Event when the state moved.
+func (r *GameServerReconciler) writeStatus(ctx context.Context, gs *gamev1alpha1.GameServer, old *gamev1alpha1.GameServerStatus) error {
+	if equality.Semantic.DeepEqual(*old, gs.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, gs); err != nil {
+		return err
+	}
+	if from, to := old.State, gs.Status.State; from != to {
+		if from == "" {
+			from = "None"
+		}
+		r.eventf(gs, corev1.EventTypeNormal, "PhaseChanged", "State changed from %s to %s with %d/%d players",
+			from, to, gs.Status.Players, gs.Status.MaxPlayers)
+	}
+	return nil
+}
+
+// eventf records an Event when a recorder is wired (tests may leave it nil).
+func (r *GameServerReconciler) eventf(obj runtime.Object, eventType, reason, format string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, eventType, reason, format, args...)
+	}
+}
+
+// checkHeartbeat marks a Push-mode server Unhealthy once heartbeats stop. The
+// heartbeat server refreshes LastHeartbeat at least every timeout/3; a new Pod's
+// start time counts as a heartbeat so it gets a full timeout to report in.
+func (r *GameServerReconciler) checkHeartbeat(ctx context.Context, gs *gamev1alpha1.GameServer, pod *corev1.Pod, now metav1.Time) (ctrl.Result, error) {
+	timeout := heartbeat.Timeout(gs)
+	last := gs.Status.LastHeartbeat
+	if st := pod.Status.StartTime; st != nil && (last == nil || st.After(last.Time)) {
+		last = st
+	}
+	if last == nil {
+		last = &now
+	}
+
+	if left := last.Add(timeout).Sub(now.Time); left > 0 {
+		old := gs.Status.DeepCopy()
+		if gs.Status.LastHeartbeat == nil || gs.Status.LastHeartbeat.Before(last) {
+			// Not heard from this Pod yet: keep it out of allocation.
+			gs.Status.NodeName = pod.Spec.NodeName
+			setState(ctx, gs, gamev1alpha1.GameServerStateRequestReady)
+		} else {
+			if gs.Status.State.IsReady() {
+				// Heartbeats say Ready; whether it is Allocated is ours to tell.
+				setState(ctx, gs, servingState(gs))
 			}
-			setOrUpdateCondition(&gs.Status.Conditions, reach)
-			gs.Status.LastPolled = &now
-			_ = r.Status().Update(ctx, &gs)
+			// The counts are this Pod's last heartbeat; no poll reports them.
+			gsPlayers.WithLabelValues(gs.Namespace, gs.Name).Set(float64(gs.Status.Players))
+			gsMaxPlayers.WithLabelValues(gs.Namespace, gs.Name).Set(float64(gs.Status.MaxPlayers))
 		}
-	} else {
-		gs.Status.Phase = phase
-		_ = r.Status().Update(ctx, &gs)
+		if lapse := applyReservation(gs, now.Time); lapse > 0 && lapse < left {
+			left = lapse
+		}
+		if err := r.writeStatus(ctx, gs, old); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: left}, nil
 	}
 
-	// Requeue to poll every 10s
+	if gs.Status.State != gamev1alpha1.GameServerStateUnhealthy {
+		old := gs.Status.DeepCopy()
+		setState(ctx, gs, gamev1alpha1.GameServerStateUnhealthy)
+		setOrUpdateCondition(&gs.Status.Conditions, metav1.Condition{
+			Type:               "Reachable",
+			Status:             metav1.ConditionFalse,
+			Reason:             "HeartbeatTimeout",
+			Message:            fmt.Sprintf("no heartbeat since %s", last.UTC().Format(time.RFC3339)),
+			LastTransitionTime: now,
+			ObservedGeneration: gs.Generation,
+		})
+		if err := r.writeStatus(ctx, gs, old); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 }
 
+// addHeartbeatCredentials gives a new Pod its own heartbeat token: the raw
+// token goes into the GameServer's Secret, which the game container's env
+// refers to, and its hash onto the Pod. The Secret also carries the heartbeat
+// server's CA when it serves TLS.
+func (r *GameServerReconciler) addHeartbeatCredentials(ctx context.Context, pod *corev1.Pod, gs *gamev1alpha1.GameServer) error {
+	token, hash, err := heartbeat.NewToken()
+	if err != nil {
+		return err
+	}
+	// Written without reading it back, so the manager never caches Secrets.
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: heartbeat.SecretName(gs.Name), Namespace: gs.Namespace},
+		Data:       map[string][]byte{heartbeat.TokenSecretKey: []byte(token)},
+	}
+	if r.HeartbeatCAFile != "" {
+		ca, err := os.ReadFile(r.HeartbeatCAFile)
+		if err != nil {
+			return err
+		}
+		secret.Data[heartbeat.CASecretKey] = ca
+	}
+	if err := ctrl.SetControllerReference(gs, &secret, r.Scheme); err != nil {
+		return err
+	}
+	err = r.Create(ctx, &secret)
+	if kerrors.IsAlreadyExists(err) {
+		err = r.Update(ctx, &secret)
+	}
+	if err != nil {
+		return err
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[heartbeat.TokenHashAnnotation] = hash
+
+	i := gameContainerIndex(pod.Spec.Containers, gs.Spec.Container)
+	c := &pod.Spec.Containers[i]
+	fromSecret := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+			Key:                  key,
+		}}
+	}
+	c.Env = setEnv(c.Env, corev1.EnvVar{Name: heartbeat.TokenEnv, ValueFrom: fromSecret(heartbeat.TokenSecretKey)})
+	if r.HeartbeatCAFile != "" {
+		c.Env = setEnv(c.Env, corev1.EnvVar{Name: heartbeat.CAEnv, ValueFrom: fromSecret(heartbeat.CASecretKey)})
+	}
+	if r.HeartbeatURL != "" {
+		url := strings.TrimSuffix(r.HeartbeatURL, "/") + heartbeat.Path(gs.Namespace, gs.Name)
+		c.Env = setEnv(c.Env, corev1.EnvVar{Name: heartbeat.URLEnv, Value: url})
+	}
+	return nil
+}
+
 func (r *GameServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&gamev1alpha1.GameServer{}).
-		Owns(&corev1.Pod{}).
-		Complete(r)
+		Owns(&corev1.Pod{})
+	if r.Poller != nil {
+		b = b.WatchesRawSource(source.Channel(r.Poller.Events(), &handler.EnqueueRequestForObject{}))
+	}
+	return b.Complete(r)
+}
+
+// buildPod renders the Pod for a GameServer: the user's template (or an empty one)
+// with the operator-owned bits laid on top of the game container. It fails when
+// spec.container names none of the template's containers.
+func buildPod(gs *gamev1alpha1.GameServer) (corev1.Pod, error) {
+	tmpl := corev1.PodTemplateSpec{}
+	if gs.Spec.Template != nil {
+		tmpl = *gs.Spec.Template.DeepCopy()
+	}
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        gs.Name,
+			Namespace:   gs.Namespace,
+			Labels:      tmpl.Labels,
+			Annotations: tmpl.Annotations,
+		},
+		Spec: tmpl.Spec,
+	}
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels["app"] = gs.Name
+	pod.Labels["game.example.com/owner"] = gs.Name
+
+	pod.Spec.HostNetwork = true
+	pod.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	if pod.Spec.RestartPolicy == "" {
+		pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	}
+	if len(gs.Spec.NodeSelector) > 0 {
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		for k, v := range gs.Spec.NodeSelector {
+			pod.Spec.NodeSelector[k] = v
+		}
+	}
+
+	i := gameContainerIndex(pod.Spec.Containers, gs.Spec.Container)
+	if i < 0 && len(pod.Spec.Containers) > 0 {
+		// Never run a made-up game container next to the user's own.
+		return corev1.Pod{}, fmt.Errorf("spec.container %q is not a container of the Pod template", gs.Spec.Container)
+	}
+	if i < 0 {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name: defaultIfEmpty(gs.Spec.Container, "server"),
+		})
+		i = len(pod.Spec.Containers) - 1
+	}
+	c := &pod.Spec.Containers[i]
+	if gs.Spec.Image != "" {
+		c.Image = gs.Spec.Image
+	}
+	c.Image = defaultIfEmpty(c.Image, "kyon/gameserver:latest")
+	for _, e := range gs.Spec.Env {
+		c.Env = setEnv(c.Env, e)
+	}
+	c.Env = setEnv(c.Env, corev1.EnvVar{Name: "GAME_PORT", Value: fmt.Sprint(gs.Spec.Port)})
+	if len(gs.Spec.Resources.Limits) > 0 || len(gs.Spec.Resources.Requests) > 0 {
+		c.Resources = gs.Spec.Resources
+	}
+	hasPort := false
+	for _, p := range c.Ports {
+		if p.ContainerPort == gs.Spec.Port {
+			hasPort = true
+			break
+		}
+	}
+	if !hasPort {
+		c.Ports = append(c.Ports, corev1.ContainerPort{ContainerPort: gs.Spec.Port})
+	}
+	if c.ReadinessProbe == nil {
+		c.ReadinessProbe = defaultReadinessProbe(gs)
+	}
+	return pod, nil
+}
+
+// defaultReadinessProbe matches the status probe protocol: HTTP GET for HTTP,
+// a TCP socket check for TCP-based protocols, and none for UDP (A2S).
+func defaultReadinessProbe(gs *gamev1alpha1.GameServer) *corev1.Probe {
+	typ := gamev1alpha1.StatusProbeHTTP
+	if gs.Spec.StatusProbe != nil && gs.Spec.StatusProbe.Type != "" {
+		typ = gs.Spec.StatusProbe.Type
+	}
+	port := gs.Spec.Port
+	if gs.Spec.StatusProbe != nil && gs.Spec.StatusProbe.Port != nil {
+		port = *gs.Spec.StatusProbe.Port
+	}
+	var handler corev1.ProbeHandler
+	switch typ {
+	case gamev1alpha1.StatusProbeHTTP:
+		handler.HTTPGet = &corev1.HTTPGetAction{
+			Path: defaultIfEmpty(gs.Spec.PollPath, "/status"),
+			Port: intstr.FromInt(int(port)),
+		}
+	case gamev1alpha1.StatusProbeTCP, gamev1alpha1.StatusProbeMinecraft:
+		handler.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(int(port))}
+	default:
+		return nil
+	}
+	return &corev1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: 2,
+		PeriodSeconds:       5,
+		TimeoutSeconds:      2,
+	}
+}
+
+// gameContainerIndex finds the game container: by name if given, else "server",
+// else the first container. -1 means the template has no usable container.
+func gameContainerIndex(cs []corev1.Container, name string) int {
+	want := defaultIfEmpty(name, "server")
+	for i := range cs {
+		if cs[i].Name == want {
+			return i
+		}
+	}
+	if name == "" && len(cs) > 0 {
+		return 0
+	}
+	return -1
+}
+
+// setEnv replaces the variable with the same name, or appends it.
+func setEnv(env []corev1.EnvVar, e corev1.EnvVar) []corev1.EnvVar {
+	for i := range env {
+		if env[i].Name == e.Name {
+			env[i] = e
+			return env
+		}
+	}
+	return append(env, e)
+}
+
+// pollFailureConditionReason maps a probe failure reason to the Reachable condition reason.
+func pollFailureConditionReason(reason string) string {
+	switch reason {
+	case probe.ReasonHTTPStatus:
+		return "BadStatus"
+	case probe.ReasonDecode:
+		return "DecodeError"
+	default:
+		return "ConnectionError"
+	}
 }
 
 func setOrUpdateCondition(conds *[]metav1.Condition, c metav1.Condition) {
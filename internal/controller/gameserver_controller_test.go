@@ -18,16 +18,30 @@ package controller
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/heartbeat"
+	"github.com/ahbeigi/gameserver-operator/internal/poller"
+	"github.com/ahbeigi/gameserver-operator/internal/probe"
 )
 
 var _ = Describe("GameServer Controller", func() {
@@ -82,3 +96,343 @@ var _ = Describe("GameServer Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("GameServer events", func() {
+	ctx := context.Background()
+
+	It("records the Pod it creates and the state change", func() {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "games"},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30001},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).
+			WithStatusSubresource(&gamev1alpha1.GameServer{}).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &GameServerReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(Equal("Normal PodCreated Created Pod gs-1 for port 30001")))
+		Expect(recorder.Events).To(Receive(Equal("Normal PhaseChanged State changed from None to Starting with 0/0 players")))
+
+		// Nothing changed, nothing to report.
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("creates no Pod when the game container is missing from the template", func() {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "games"},
+			Spec: gamev1alpha1.GameServerSpec{Port: 30001, Container: "gmae", Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "game", Image: "my/game:1"}}},
+			}},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).
+			WithStatusSubresource(&gamev1alpha1.GameServer{}).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &GameServerReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gs)})
+		Expect(err).To(MatchError(reconcile.TerminalError(nil)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(`Warning InvalidTemplate Cannot create Pod: spec.container "gmae"`)))
+		var pods corev1.PodList
+		Expect(c.List(ctx, &pods)).To(Succeed())
+		Expect(pods.Items).To(BeEmpty())
+	})
+})
+
+var _ = Describe("buildPod", func() {
+	It("overlays operator-owned fields onto the user's template", func() {
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "default"},
+			Spec: gamev1alpha1.GameServerSpec{
+				Port:      30001,
+				Container: "game",
+				Template: &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "blue"}},
+					Spec: corev1.PodSpec{
+						ServiceAccountName: "gs-sa",
+						Containers: []corev1.Container{
+							{Name: "log-shipper", Image: "fluentbit"},
+							{Name: "game", Image: "my/game:1", Env: []corev1.EnvVar{{Name: "GAME_PORT", Value: "1"}}},
+						},
+					},
+				},
+			},
+		}
+
+		pod, err := buildPod(gs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.HostNetwork).To(BeTrue())
+		Expect(pod.Spec.ServiceAccountName).To(Equal("gs-sa"))
+		Expect(pod.Labels).To(HaveKeyWithValue("team", "blue"))
+		Expect(pod.Labels).To(HaveKeyWithValue("game.example.com/owner", "gs-1"))
+		Expect(pod.Spec.Containers[0].Env).To(BeEmpty())
+
+		game := pod.Spec.Containers[1]
+		Expect(game.Image).To(Equal("my/game:1"))
+		Expect(game.Env).To(ConsistOf(corev1.EnvVar{Name: "GAME_PORT", Value: "30001"}))
+		Expect(game.Ports).To(ContainElement(corev1.ContainerPort{ContainerPort: 30001}))
+		Expect(game.ReadinessProbe).NotTo(BeNil())
+	})
+
+	It("refuses a game container the template does not have", func() {
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "default"},
+			Spec: gamev1alpha1.GameServerSpec{
+				Port:      30001,
+				Container: "gmae",
+				Template: &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "game", Image: "my/game:1"}},
+				}},
+			},
+		}
+		_, err := buildPod(gs)
+		Expect(err).To(MatchError(ContainSubstring(`spec.container "gmae"`)))
+
+		gs.Spec.Template = nil
+		pod, err := buildPod(gs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Spec.Containers).To(ConsistOf(HaveField("Name", "gmae")))
+	})
+})
+
+var _ = Describe("addHeartbeatCredentials", func() {
+	It("keeps the token in a Secret the game container refers to and its hash on the Pod", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "games", UID: "gs-uid"},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30001, StatusMode: gamev1alpha1.StatusModePush},
+		}
+		r := &GameServerReconciler{Client: c, Scheme: scheme, HeartbeatURL: "http://hb.svc:8090/"}
+		// token reads back the Secret of the Pod built last.
+		token := func() string {
+			var secret corev1.Secret
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "games", Name: "gs-1-heartbeat"}, &secret)).To(Succeed())
+			Expect(secret.OwnerReferences).To(ConsistOf(HaveField("UID", gs.UID)))
+			return string(secret.Data[heartbeat.TokenSecretKey])
+		}
+
+		pod, err := buildPod(gs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.addHeartbeatCredentials(ctx, &pod, gs)).To(Succeed())
+
+		env := map[string]corev1.EnvVar{}
+		for _, e := range pod.Spec.Containers[0].Env {
+			env[e.Name] = e
+		}
+		Expect(env[heartbeat.URLEnv].Value).To(Equal("http://hb.svc:8090/v1/heartbeat/games/gs-1"))
+		Expect(env[heartbeat.TokenEnv].Value).To(BeEmpty())
+		Expect(env[heartbeat.TokenEnv].ValueFrom.SecretKeyRef.Name).To(Equal("gs-1-heartbeat"))
+		Expect(env[heartbeat.TokenEnv].ValueFrom.SecretKeyRef.Key).To(Equal(heartbeat.TokenSecretKey))
+		first := token()
+		Expect(pod.Annotations).To(HaveKeyWithValue(heartbeat.TokenHashAnnotation, heartbeat.HashToken(first)))
+
+		// A replacement Pod gets a fresh token; the old one stops matching.
+		pod, err = buildPod(gs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.addHeartbeatCredentials(ctx, &pod, gs)).To(Succeed())
+		Expect(token()).NotTo(Equal(first))
+		Expect(pod.Annotations).To(HaveKeyWithValue(heartbeat.TokenHashAnnotation, heartbeat.HashToken(token())))
+	})
+
+	It("hands the CA of a TLS heartbeat server to the game container", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-1", Namespace: "games", UID: "gs-uid"},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30001, StatusMode: gamev1alpha1.StatusModePush},
+		}
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(caFile, []byte("-----BEGIN CERTIFICATE-----\n"), 0o600)).To(Succeed())
+		r := &GameServerReconciler{Client: c, Scheme: scheme, HeartbeatURL: "https://hb.svc:8090", HeartbeatCAFile: caFile}
+
+		pod, err := buildPod(gs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.addHeartbeatCredentials(ctx, &pod, gs)).To(Succeed())
+
+		var secret corev1.Secret
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "games", Name: "gs-1-heartbeat"}, &secret)).To(Succeed())
+		Expect(string(secret.Data[heartbeat.CASecretKey])).To(Equal("-----BEGIN CERTIFICATE-----\n"))
+		Expect(pod.Spec.Containers[0].Env).To(ContainElement(SatisfyAll(
+			HaveField("Name", heartbeat.CAEnv),
+			HaveField("ValueFrom.SecretKeyRef.Name", "gs-1-heartbeat"),
+			HaveField("ValueFrom.SecretKeyRef.Key", heartbeat.CASecretKey),
+		)))
+	})
+})
+
+var _ = Describe("checkHeartbeat", func() {
+	It("reports the pushed player counts as metrics", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		heard := metav1.NewTime(time.Now().Add(-time.Second))
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs-push", Namespace: "games"},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30001, StatusMode: gamev1alpha1.StatusModePush},
+			Status: gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateReady,
+				Players: 6, MaxPlayers: 16, LastHeartbeat: &heard},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).
+			WithStatusSubresource(&gamev1alpha1.GameServer{}).Build()
+		r := &GameServerReconciler{Client: c, Scheme: scheme}
+		started := metav1.NewTime(time.Now().Add(-time.Minute))
+		pod := &corev1.Pod{Status: corev1.PodStatus{StartTime: &started}}
+
+		_, err := r.checkHeartbeat(ctx, gs, pod, metav1.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(gsPlayers.WithLabelValues("games", "gs-push"))).To(Equal(6.0))
+		Expect(testutil.ToFloat64(gsMaxPlayers.WithLabelValues("games", "gs-push"))).To(Equal(16.0))
+		forgetGameServerMetrics("games", "gs-push")
+	})
+})
+
+var _ = Describe("applyObservation", func() {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{NodeName: "node-a"}}
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	It("copies counts and is idempotent", func() {
+		gs := &gamev1alpha1.GameServer{}
+		obs := poller.Observation{At: at, Endpoint: "http://10.0.0.1:30000/status",
+			Result: probe.Result{Players: 0, MaxPlayers: 8, HasCounts: true}}
+		applyObservation(gs, pod, obs)
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReady))
+		Expect(gs.Status.Phase).To(Equal("Running"))
+		Expect(gs.Status.MaxPlayers).To(Equal(int32(8)))
+		Expect(gs.Status.ZeroSince.Time).To(Equal(at))
+
+		again := gs.DeepCopy()
+		applyObservation(again, pod, obs)
+		Expect(again.Status).To(Equal(gs.Status))
+	})
+
+	It("marks failures Unhealthy without touching counts", func() {
+		gs := &gamev1alpha1.GameServer{Status: gamev1alpha1.GameServerStatus{Players: 5}}
+		obs := poller.Observation{At: at, Err: &probe.Error{Reason: probe.ReasonHTTPStatus, Err: errors.NewBadRequest("x")}}
+		applyObservation(gs, pod, obs)
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateUnhealthy))
+		Expect(gs.Status.Players).To(Equal(int32(5)))
+		Expect(gs.Status.Conditions).To(ContainElement(HaveField("Reason", "BadStatus")))
+	})
+
+	It("marks counts unknown for a liveness-only probe", func() {
+		gs := &gamev1alpha1.GameServer{Status: gamev1alpha1.GameServerStatus{ZeroSince: &metav1.Time{Time: at}}}
+		applyObservation(gs, pod, poller.Observation{At: at, Result: probe.Result{}})
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReady))
+		Expect(gs.Status.PlayersUnknown).To(BeTrue())
+		Expect(gs.Status.ZeroSince).To(BeNil())
+		Expect(isEmpty(gs)).To(BeFalse())
+
+		// With Both, pushes report the counts; the poll leaves them alone.
+		both := &gamev1alpha1.GameServer{Spec: gamev1alpha1.GameServerSpec{StatusMode: gamev1alpha1.StatusModeBoth}}
+		applyObservation(both, pod, poller.Observation{At: at, Result: probe.Result{}})
+		Expect(both.Status.PlayersUnknown).To(BeFalse())
+
+		applyObservation(gs, pod, poller.Observation{At: at, Result: probe.Result{MaxPlayers: 8, HasCounts: true}})
+		Expect(gs.Status.PlayersUnknown).To(BeFalse())
+		Expect(isEmpty(gs)).To(BeTrue())
+	})
+
+	It("reports an allocated server as Allocated", func() {
+		gs := &gamev1alpha1.GameServer{Status: gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateReady}}
+		gs.Annotations = map[string]string{allocatedAnno: "match-1"}
+		applyObservation(gs, pod, poller.Observation{At: at, Result: probe.Result{Players: 2, MaxPlayers: 8, HasCounts: true}})
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateAllocated))
+	})
+
+	It("never revives a server that shut down", func() {
+		gs := &gamev1alpha1.GameServer{Status: gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateShutdown}}
+		applyObservation(gs, pod, poller.Observation{At: at, Result: probe.Result{HasCounts: true}})
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateShutdown))
+	})
+})
+
+var _ = Describe("applyReservation", func() {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	reserved := func(state gamev1alpha1.GameServerState, until time.Time) *gamev1alpha1.GameServer {
+		gs := &gamev1alpha1.GameServer{Status: gamev1alpha1.GameServerStatus{State: state}}
+		gs.Annotations = map[string]string{
+			gamev1alpha1.ReservedByAnnotation:    "lobby-7",
+			gamev1alpha1.ReservedUntilAnnotation: until.Format(time.RFC3339),
+		}
+		return gs
+	}
+
+	It("holds a Ready server and says when to come back", func() {
+		gs := reserved(gamev1alpha1.GameServerStateReady, now.Add(45*time.Second))
+		Expect(applyReservation(gs, now)).To(Equal(45 * time.Second))
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReserved))
+		Expect(gs.Status.ReservedBy).To(Equal("lobby-7"))
+		Expect(gs.Status.ReservedUntil.Time).To(BeTemporally("==", now.Add(45*time.Second)))
+	})
+
+	It("lapses back to Ready", func() {
+		gs := reserved(gamev1alpha1.GameServerStateReserved, now)
+		gs.Status.ReservedBy = "lobby-7"
+		Expect(applyReservation(gs, now)).To(BeZero())
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReady))
+		Expect(gs.Status.ReservedBy).To(BeEmpty())
+		Expect(gs.Status.ReservedUntil).To(BeNil())
+	})
+
+	It("leaves servers that are not serving alone", func() {
+		gs := reserved(gamev1alpha1.GameServerStateRequestReady, now.Add(time.Minute))
+		applyReservation(gs, now)
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateRequestReady))
+	})
+
+	It("gives way to an allocation", func() {
+		gs := reserved(gamev1alpha1.GameServerStateAllocated, now.Add(time.Minute))
+		Expect(applyReservation(gs, now)).To(BeZero())
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateAllocated))
+		Expect(gs.Status.ReservedBy).To(BeEmpty())
+	})
+})
+
+var _ = Describe("GameServer states", func() {
+	DescribeTable("transitions",
+		func(from, to gamev1alpha1.GameServerState, ok bool) {
+			Expect(from.CanTransitionTo(to)).To(Equal(ok))
+		},
+		Entry("unset to anything", gamev1alpha1.GameServerState(""), gamev1alpha1.GameServerStateAllocated, true),
+		Entry("start-up may skip ahead", gamev1alpha1.GameServerStateCreating, gamev1alpha1.GameServerStateReady, true),
+		Entry("Ready to Allocated", gamev1alpha1.GameServerStateReady, gamev1alpha1.GameServerStateAllocated, true),
+		Entry("Ready never back to Scheduled", gamev1alpha1.GameServerStateReady, gamev1alpha1.GameServerStateScheduled, false),
+		Entry("anything to Shutdown", gamev1alpha1.GameServerStateStarting, gamev1alpha1.GameServerStateShutdown, true),
+		Entry("Shutdown is final", gamev1alpha1.GameServerStateShutdown, gamev1alpha1.GameServerStateReady, false),
+		Entry("Unhealthy restarts", gamev1alpha1.GameServerStateUnhealthy, gamev1alpha1.GameServerStateStarting, true),
+	)
+
+	It("keeps the deprecated phase in step", func() {
+		for state, phase := range map[gamev1alpha1.GameServerState]string{
+			gamev1alpha1.GameServerStateStarting:  "Pending",
+			gamev1alpha1.GameServerStateReady:     "Running",
+			gamev1alpha1.GameServerStateUnhealthy: "Unreachable",
+			gamev1alpha1.GameServerStateShutdown:  "Terminating",
+		} {
+			st := gamev1alpha1.GameServerStatus{}
+			Expect(st.SetState(state)).To(BeTrue())
+			Expect(st.Phase).To(Equal(phase))
+		}
+
+		st := gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateShutdown}
+		Expect(st.SetState(gamev1alpha1.GameServerStateReady)).To(BeFalse())
+		Expect(st.State).To(Equal(gamev1alpha1.GameServerStateShutdown))
+	})
+})
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserverallocations,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserverallocations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserverallocations/finalizers,verbs=update
+//+kubebuilder:rbac:groups=game.example.com,resources=gameservers,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=game.example.com,resources=gameservers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=game.example.com,resources=gsdeployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// allocationFinalizer holds a GameServerAllocation until its server is released.
+const allocationFinalizer = "game.example.com/allocation"
+
+// GameServerAllocationReconciler answers each GameServerAllocation exactly once:
+// it picks a Ready, non-draining, unallocated GameServer from the requested
+// GSDeployment (from its active revision during a BlueGreen rollout), marks it Allocated and writes the endpoint into the allocation's status.
+// Deleting the allocation releases the server back to the pool.
+type GameServerAllocationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *GameServerAllocationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+
+	var gsa gamev1alpha1.GameServerAllocation
+	if err := r.Get(ctx, req.NamespacedName, &gsa); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !gsa.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.release(ctx, &gsa)
+	}
+	// Create-only (the webhook rejects spec changes): a request that already
+	// has an answer is never re-evaluated. Allocations answered before the
+	// finalizer existed still get it, so their server is released too.
+	if gsa.Status.State != "" {
+		if gsa.Status.State == gamev1alpha1.AllocationStateAllocated {
+			return ctrl.Result{}, r.addFinalizer(ctx, &gsa)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	sel := labels.SelectorFromSet(childLabels(gsa.Spec.GSDeployment))
+	if gsa.Spec.Selector != nil {
+		extra, err := metav1.LabelSelectorAsSelector(gsa.Spec.Selector)
+		if err != nil {
+			gsa.Status.State = gamev1alpha1.AllocationStateUnAllocated
+			gsa.Status.Message = fmt.Sprintf("invalid selector: %v", err)
+			return ctrl.Result{}, r.Status().Update(ctx, &gsa)
+		}
+		reqs, _ := extra.Requirements()
+		sel = sel.Add(reqs...)
+	}
+
+	var children gamev1alpha1.GameServerList
+	if err := r.List(ctx, &children, client.InNamespace(gsa.Namespace),
+		client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return ctrl.Result{}, err
+	}
+	// A server already carrying our reference was claimed by an earlier attempt
+	// whose status write failed; answer with it instead of claiming another.
+	// The UID keeps a recreated allocation of the same name off the old server.
+	for i := range children.Items {
+		if children.Items[i].GetAnnotations()[allocatedAnno] == allocationRef(&gsa) {
+			return ctrl.Result{}, r.allocated(ctx, &gsa, &children.Items[i])
+		}
+	}
+
+	// A BlueGreen fleet only allocates from its active revision, so the switch
+	// to a new one happens at once.
+	var gsd gamev1alpha1.GSDeployment
+	if err := r.Get(ctx, types.NamespacedName{Namespace: gsa.Namespace, Name: gsa.Spec.GSDeployment}, &gsd); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	} else if rev := activeRevision(&gsd); rev != "" {
+		children.Items = onRevision(children.Items, rev)
+	}
+
+	candidates := allocatable(children.Items)
+	if len(candidates) == 0 {
+		gsa.Status.State = gamev1alpha1.AllocationStateUnAllocated
+		gsa.Status.Message = "no Ready GameServer available"
+		return ctrl.Result{}, r.Status().Update(ctx, &gsa)
+	}
+	// The finalizer goes on before any server is claimed, so none is leaked.
+	if err := r.addFinalizer(ctx, &gsa); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Mark the first candidate we can win. The Update carries the resourceVersion
+	// we listed, so a concurrent allocation of the same server fails with a
+	// Conflict and we simply move on to the next one.
+	for i := range candidates {
+		gs := candidates[i]
+		anno := gs.GetAnnotations()
+		if anno == nil {
+			anno = map[string]string{}
+		}
+		anno[allocatedAnno] = allocationRef(&gsa)
+		gs.SetAnnotations(anno)
+		if err := r.Update(ctx, &gs); err != nil {
+			if kerrors.IsConflict(err) || kerrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+		log.Info("allocated", "gameserver", gs.Name, "allocation", gsa.Name)
+		return ctrl.Result{}, r.allocated(ctx, &gsa, &gs)
+	}
+
+	gsa.Status.State = gamev1alpha1.AllocationStateContention
+	gsa.Status.Message = "all candidates were allocated concurrently"
+	return ctrl.Result{}, r.Status().Update(ctx, &gsa)
+}
+
+// addFinalizer makes sure deleting gsa waits for release.
+func (r *GameServerAllocationReconciler) addFinalizer(ctx context.Context, gsa *gamev1alpha1.GameServerAllocation) error {
+	if !controllerutil.AddFinalizer(gsa, allocationFinalizer) {
+		return nil
+	}
+	return r.Update(ctx, gsa)
+}
+
+// release hands the servers a deleted allocation holds back to the pool:
+// the allocated annotation goes, and an Allocated server is Ready again, so
+// the scale-down and the rollout may remove it. Then the finalizer goes.
+func (r *GameServerAllocationReconciler) release(ctx context.Context, gsa *gamev1alpha1.GameServerAllocation) error {
+	if !controllerutil.ContainsFinalizer(gsa, allocationFinalizer) {
+		return nil
+	}
+	var children gamev1alpha1.GameServerList
+	if err := r.List(ctx, &children, client.InNamespace(gsa.Namespace),
+		client.MatchingLabels(childLabels(gsa.Spec.GSDeployment))); err != nil {
+		return err
+	}
+	for i := range children.Items {
+		gs := &children.Items[i]
+		if gs.GetAnnotations()[allocatedAnno] != allocationRef(gsa) {
+			continue
+		}
+		delete(gs.Annotations, allocatedAnno)
+		if err := r.Update(ctx, gs); kerrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if gs.Status.State == gamev1alpha1.GameServerStateAllocated {
+			gs.Status.SetState(gamev1alpha1.GameServerStateReady)
+			if err := r.Status().Update(ctx, gs); err != nil && !kerrors.IsNotFound(err) {
+				return err
+			}
+		}
+		ctrllog.FromContext(ctx).Info("released", "gameserver", gs.Name, "allocation", gsa.Name)
+	}
+	controllerutil.RemoveFinalizer(gsa, allocationFinalizer)
+	return r.Update(ctx, gsa)
+}
+
+// allocationRef is what the allocated annotation holds: name/uid of the
+// allocation, so a later allocation reusing the name is a different holder.
+func allocationRef(gsa *gamev1alpha1.GameServerAllocation) string {
+	return gsa.Name + "/" + string(gsa.UID)
+}
+
+// allocated writes gs's endpoint into the allocation's status.
+func (r *GameServerAllocationReconciler) allocated(ctx context.Context, gsa *gamev1alpha1.GameServerAllocation,
+	gs *gamev1alpha1.GameServer) error {
+	gsa.Status.State = gamev1alpha1.AllocationStateAllocated
+	gsa.Status.GameServerName = gs.Name
+	gsa.Status.Port = gs.Spec.Port
+	gsa.Status.NodeName = gs.Status.NodeName
+	var pod corev1.Pod
+	if err := r.Get(ctx, types.NamespacedName{Name: gs.Name, Namespace: gs.Namespace}, &pod); err == nil {
+		gsa.Status.Address = pod.Status.HostIP
+		gsa.Status.NodeName = pod.Spec.NodeName
+	}
+	return r.Status().Update(ctx, gsa)
+}
+
+func (r *GameServerAllocationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gamev1alpha1.GameServerAllocation{}).
+		Complete(r)
+}
+
+// allocatable returns Ready, non-draining, unallocated and unreserved servers, emptiest first
+// (ties broken by age) so a fresh match lands on an empty server.
+func allocatable(list []gamev1alpha1.GameServer) []gamev1alpha1.GameServer {
+	out := make([]gamev1alpha1.GameServer, 0, len(list))
+	for _, gs := range list {
+		if gs.Status.State != gamev1alpha1.GameServerStateReady || !gs.DeletionTimestamp.IsZero() {
+			continue
+		}
+		// The state may not show a fresh reservation yet.
+		if isDraining(&gs) || isAllocated(&gs) || isReserved(&gs) {
+			continue
+		}
+		out = append(out, gs)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Status.Players != out[j].Status.Players {
+			return out[i].Status.Players < out[j].Status.Players
+		}
+		return out[i].CreationTimestamp.Before(&out[j].CreationTimestamp)
+	})
+	return out
+}
+
+// onRevision keeps the servers of one template revision.
+func onRevision(list []gamev1alpha1.GameServer, revision string) []gamev1alpha1.GameServer {
+	out := make([]gamev1alpha1.GameServer, 0, len(list))
+	for _, gs := range list {
+		if gs.Labels[gamev1alpha1.RevisionLabel] == revision {
+			out = append(out, gs)
+		}
+	}
+	return out
+}
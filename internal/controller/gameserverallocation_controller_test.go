@@ -0,0 +1,278 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("GameServerAllocation Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-allocation"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		allocation := &gamev1alpha1.GameServerAllocation{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind GameServerAllocation")
+			err := k8sClient.Get(ctx, typeNamespacedName, allocation)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &gamev1alpha1.GameServerAllocation{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: gamev1alpha1.GameServerAllocationSpec{GSDeployment: "no-such-fleet"},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &gamev1alpha1.GameServerAllocation{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance GameServerAllocation")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should report UnAllocated when the fleet has no Ready servers", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &GameServerAllocationReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			got := &gamev1alpha1.GameServerAllocation{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, got)).To(Succeed())
+			Expect(got.Status.State).To(Equal(gamev1alpha1.AllocationStateUnAllocated))
+		})
+	})
+})
+
+var _ = Describe("allocating a GameServer", func() {
+	ctx := context.Background()
+
+	// newClient holds objs and a fresh "match" allocation on the "fleet" GSDeployment.
+	newClient := func(funcs interceptor.Funcs, objs ...client.Object) client.Client {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		objs = append(objs, &gamev1alpha1.GameServerAllocation{
+			ObjectMeta: metav1.ObjectMeta{Name: "match", Namespace: "default", UID: "match-uid"},
+			Spec:       gamev1alpha1.GameServerAllocationSpec{GSDeployment: "fleet"},
+		})
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithStatusSubresource(&gamev1alpha1.GameServerAllocation{}, &gamev1alpha1.GameServer{}).WithInterceptorFuncs(funcs).Build()
+	}
+	// allocate reconciles the allocation once and returns it as stored.
+	allocate := func(c client.Client) (*gamev1alpha1.GameServerAllocation, error) {
+		r := &GameServerAllocationReconciler{Client: c, Scheme: c.Scheme()}
+		key := types.NamespacedName{Namespace: "default", Name: "match"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		gsa := &gamev1alpha1.GameServerAllocation{}
+		Expect(c.Get(ctx, key, gsa)).To(Succeed())
+		return gsa, err
+	}
+	// claimedBy lists the servers the "match" allocation holds.
+	claimedBy := func(c client.Client) []string {
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list)).To(Succeed())
+		var out []string
+		for _, gs := range list.Items {
+			if gs.Annotations[allocatedAnno] == "match/match-uid" {
+				out = append(out, gs.Name)
+			}
+		}
+		return out
+	}
+	ready := func(i int, annos ...string) *gamev1alpha1.GameServer {
+		gs := fleetServer(i, gamev1alpha1.GameServerStateReady, 0, annos...)
+		return &gs
+	}
+
+	It("marks the server and answers with its endpoint", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "fleet-30000", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node-a"},
+			Status:     corev1.PodStatus{HostIP: "10.0.0.7"},
+		}
+		c := newClient(interceptor.Funcs{}, ready(0), pod)
+
+		gsa, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.State).To(Equal(gamev1alpha1.AllocationStateAllocated))
+		Expect(gsa.Status.GameServerName).To(Equal("fleet-30000"))
+		Expect(gsa.Status.Port).To(Equal(int32(30000)))
+		Expect(gsa.Status.Address).To(Equal("10.0.0.7"))
+		Expect(gsa.Status.NodeName).To(Equal("node-a"))
+		Expect(claimedBy(c)).To(ConsistOf("fleet-30000"))
+	})
+
+	It("skips draining, allocated and reserved servers", func() {
+		reserved := ready(2)
+		reserved.Annotations = map[string]string{
+			gamev1alpha1.ReservedUntilAnnotation: time.Now().Add(time.Minute).UTC().Format(time.RFC3339),
+		}
+		taken := []client.Object{ready(0, drainAnno), ready(1, allocatedAnno), reserved}
+
+		gsa, err := allocate(newClient(interceptor.Funcs{}, taken...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.State).To(Equal(gamev1alpha1.AllocationStateUnAllocated))
+
+		c := newClient(interceptor.Funcs{}, append(taken, ready(3))...)
+		gsa, err = allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.GameServerName).To(Equal("fleet-30003"))
+		Expect(claimedBy(c)).To(ConsistOf("fleet-30003"))
+	})
+
+	It("reports Contention when every candidate is claimed first", func() {
+		lose := interceptor.Funcs{Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*gamev1alpha1.GameServer); ok {
+				return errors.NewConflict(schema.GroupResource{Resource: "gameservers"}, obj.GetName(), nil)
+			}
+			return c.Update(ctx, obj, opts...)
+		}}
+
+		gsa, err := allocate(newClient(lose, ready(0), ready(1)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.State).To(Equal(gamev1alpha1.AllocationStateContention))
+	})
+
+	It("answers a retry with the server it already claimed", func() {
+		failed := false
+		failOnce := interceptor.Funcs{SubResourceUpdate: func(ctx context.Context, c client.Client, sub string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			if !failed {
+				failed = true
+				return errors.NewTimeoutError("status write", 1)
+			}
+			return c.Status().Update(ctx, obj, opts...)
+		}}
+		c := newClient(failOnce, ready(0), ready(1))
+
+		_, err := allocate(c)
+		Expect(err).To(HaveOccurred())
+		gsa, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.State).To(Equal(gamev1alpha1.AllocationStateAllocated))
+		Expect(claimedBy(c)).To(ConsistOf(gsa.Status.GameServerName))
+	})
+
+	It("does not answer with a server held by an earlier allocation of the same name", func() {
+		old := ready(0)
+		old.Annotations = map[string]string{allocatedAnno: "match/old-uid"}
+		c := newClient(interceptor.Funcs{}, old, ready(1))
+
+		gsa, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Status.GameServerName).To(Equal("fleet-30001"))
+		Expect(claimedBy(c)).To(ConsistOf("fleet-30001"))
+	})
+
+	It("releases the server when the allocation is deleted", func() {
+		c := newClient(interceptor.Funcs{}, ready(0))
+		gsa, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gsa.Finalizers).To(ContainElement(allocationFinalizer))
+
+		// The server reported in as Allocated meanwhile.
+		var gs gamev1alpha1.GameServer
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "fleet-30000"}, &gs)).To(Succeed())
+		gs.Status.State = gamev1alpha1.GameServerStateAllocated
+		Expect(c.Status().Update(ctx, &gs)).To(Succeed())
+
+		Expect(c.Delete(ctx, gsa)).To(Succeed())
+		r := &GameServerAllocationReconciler{Client: c, Scheme: c.Scheme()}
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsa)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsa), gsa)).To(MatchError(errors.IsNotFound, "IsNotFound"))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&gs), &gs)).To(Succeed())
+		Expect(gs.Annotations).NotTo(HaveKey(allocatedAnno))
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReady))
+		Expect(isAllocated(&gs)).To(BeFalse())
+	})
+
+	It("adds the finalizer to an allocation answered before it existed", func() {
+		c := newClient(interceptor.Funcs{}, ready(0))
+		var gsa gamev1alpha1.GameServerAllocation
+		key := types.NamespacedName{Namespace: "default", Name: "match"}
+		Expect(c.Get(ctx, key, &gsa)).To(Succeed())
+		gsa.Status.State = gamev1alpha1.AllocationStateAllocated
+		Expect(c.Status().Update(ctx, &gsa)).To(Succeed())
+
+		got, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Finalizers).To(ContainElement(allocationFinalizer))
+		Expect(claimedBy(c)).To(BeEmpty())
+	})
+
+	It("keeps the allocated server through a scale-down", func() {
+		var objs []client.Object
+		for i := range 3 {
+			gs := ready(i)
+			gs.Status.ZeroSince = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			objs = append(objs, gs)
+		}
+		c := newClient(interceptor.Funcs{}, objs...)
+		gsa, err := allocate(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		gsd := bufferFleet(intstr.FromInt32(0), 0, 10)
+		gsd.Spec.Scaling = nil
+		gsd.Spec.ScaleUpThresholdPercent, gsd.Spec.ScaleDownZeroSeconds = 80, 60
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list)).To(Succeed())
+		r := &GSDeploymentReconciler{Client: c, Scheme: c.Scheme()}
+		_, _, err = r.scaleThreshold(ctx, gsd, list.Items, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.List(ctx, &list)).To(Succeed())
+		Expect(list.Items).To(ConsistOf(HaveField("Name", gsa.Status.GameServerName)))
+	})
+})
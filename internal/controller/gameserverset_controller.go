@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserversets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserversets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=game.example.com,resources=gameservers,verbs=get;list;watch
+
+// GameServerSetReconciler keeps each GameServerSet's status counts current.
+// Servers are created and removed by the owning GSDeployment, which moves
+// the fleet between sets during a rollout.
+type GameServerSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var set gamev1alpha1.GameServerSet
+	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var children gamev1alpha1.GameServerList
+	if err := r.List(ctx, &children, client.InNamespace(set.Namespace),
+		client.MatchingLabels{gamev1alpha1.RevisionLabel: set.Spec.Revision}); err != nil {
+		return ctrl.Result{}, err
+	}
+	status := setStatus(&set, children.Items)
+	if equality.Semantic.DeepEqual(status, set.Status) {
+		return ctrl.Result{}, nil
+	}
+	set.Status = status
+	return ctrl.Result{}, client.IgnoreNotFound(r.Status().Update(ctx, &set))
+}
+
+// setStatus counts the servers set controls.
+func setStatus(set *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) gamev1alpha1.GameServerSetStatus {
+	var st gamev1alpha1.GameServerSetStatus
+	for _, gs := range children {
+		if !metav1.IsControlledBy(&gs, set) {
+			continue
+		}
+		st.Replicas++
+		if isReady(&gs) {
+			st.ReadyReplicas++
+		}
+		if isAllocated(&gs) {
+			st.AllocatedReplicas++
+		}
+		if isDraining(&gs) {
+			st.DrainingReplicas++
+		}
+		st.Players += gs.Status.Players
+	}
+	return st
+}
+
+func (r *GameServerSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Counts follow the servers' status and the draining/allocated annotations.
+	counted := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, ok1 := e.ObjectOld.(*gamev1alpha1.GameServer)
+			newObj, ok2 := e.ObjectNew.(*gamev1alpha1.GameServer)
+			if !ok1 || !ok2 {
+				return true
+			}
+			return !equality.Semantic.DeepEqual(oldObj.Status, newObj.Status) ||
+				!equality.Semantic.DeepEqual(oldObj.Annotations, newObj.Annotations)
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gamev1alpha1.GameServerSet{}).
+		Owns(&gamev1alpha1.GameServer{}, builder.WithPredicates(counted)).
+		Complete(r)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("setStatus", func() {
+	It("counts only the servers the set controls", func() {
+		set := fleetSet()
+		set.APIVersion, set.Kind = gamev1alpha1.GroupVersion.String(), "GameServerSet"
+		own := func(gs gamev1alpha1.GameServer) gamev1alpha1.GameServer {
+			gs.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: set.APIVersion, Kind: set.Kind, Name: set.Name, UID: set.UID, Controller: ptr.To(true),
+			}}
+			return gs
+		}
+		children := []gamev1alpha1.GameServer{
+			own(fleetServer(0, gamev1alpha1.GameServerStateReady, 3)),
+			own(fleetServer(1, gamev1alpha1.GameServerStateAllocated, 5, allocatedAnno)),
+			own(fleetServer(2, gamev1alpha1.GameServerStateStarting, 0, drainAnno)),
+			fleetServer(3, gamev1alpha1.GameServerStateReady, 7), // another fleet's twin revision
+		}
+		Expect(setStatus(set, children)).To(Equal(gamev1alpha1.GameServerSetStatus{
+			Replicas: 3, ReadyReplicas: 2, AllocatedReplicas: 1, DrainingReplicas: 1, Players: 8,
+		}))
+	})
+})
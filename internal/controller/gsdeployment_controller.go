@@ -4,35 +4,61 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/autoscaler"
+	"github.com/ahbeigi/gameserver-operator/internal/schedule"
 
 	corev1 "k8s.io/api/core/v1" // added
 	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 //+kubebuilder:rbac:groups=game.example.com,resources=gsdeployments,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=game.example.com,resources=gsdeployments/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=game.example.com,resources=gsdeployments/finalizers,verbs=update
 //+kubebuilder:rbac:groups=game.example.com,resources=gameservers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 
 type GSDeploymentReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	scaleHooks *autoscaler.Client
+	historyMu  sync.Mutex
+	histories  map[types.NamespacedName]*fleetHistory
 }
 
 const (
-	drainAnno = "game.example.com/draining" // "true" → allocator should avoid
+	drainAnno      = "game.example.com/draining"       // "true" → allocator should avoid
+	allocatedAnno  = "game.example.com/allocated"      // name/uid of the GameServerAllocation holding the server
+	drainSinceAnno = "game.example.com/draining-since" // RFC3339 time the server was marked draining
+
+	condForceDrained    = "ForceDrained"
+	condScalingFallback = "ScalingFallback"   // Webhook policy: built-in rule in use
+	condAvailable       = "Available"         // enough servers serve
+	condProgressing     = "Progressing"       // rollout state
+	condReplicaFailure  = "ReplicaFailure"    // servers could not be created or are Unhealthy
+	condPaused          = "Paused"            // spec.paused
+	condScalingPaused   = "AutoscalingPaused" // spec.autoscalingPaused
 )
 
 func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -42,9 +68,18 @@ func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// Fetch parent GSDeployment
 	var gsd gamev1alpha1.GSDeployment
 	if err := r.Get(ctx, req.NamespacedName, &gsd); err != nil {
+		if kerrors.IsNotFound(err) {
+			forgetFleetMetrics(req.Namespace, req.Name)
+			r.forgetHistory(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// A rollback rewrites the spec; the update brings us back to roll it out.
+	if gsd.Spec.RollbackTo != nil {
+		return ctrl.Result{}, r.rollback(ctx, &gsd)
+	}
+
 	// Defaults
 	if gsd.Spec.ScaleUpThresholdPercent == 0 {
 		gsd.Spec.ScaleUpThresholdPercent = 80
@@ -52,31 +87,36 @@ func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if gsd.Spec.ScaleDownZeroSeconds == 0 {
 		gsd.Spec.ScaleDownZeroSeconds = 60
 	}
-	if gsd.Spec.PollPath == "" {
-		gsd.Spec.PollPath = "/status"
-	}
-	if gsd.Spec.Image == "" {
-		gsd.Spec.Image = "kyon/gameserver:latest"
-	}
+	defaultTemplate(&gsd.Spec)
 	// Simple UpdateStrategy defaults
 	if gsd.Spec.UpdateStrategy.Type == "" {
-		gsd.Spec.UpdateStrategy.Type = "NoDisruption"
+		gsd.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyNoDisruption
 	}
 	if gsd.Spec.UpdateStrategy.DrainTimeoutSeconds == 0 {
 		gsd.Spec.UpdateStrategy.DrainTimeoutSeconds = 7200
 	}
-	if gsd.Spec.UpdateStrategy.MaxSurge == 0 {
-		gsd.Spec.UpdateStrategy.MaxSurge = 2
+	if gsd.Spec.UpdateStrategy.MaxUnavailable == nil {
+		unavailable := intstr.FromInt32(0)
+		gsd.Spec.UpdateStrategy.MaxUnavailable = &unavailable
 	}
-	if gsd.Spec.UpdateStrategy.MaxUnavailable == 0 {
-		gsd.Spec.UpdateStrategy.MaxUnavailable = 0
+	// Before maxSurge took percentages a 0 meant the default. Fleets stored
+	// that way have no maxUnavailable either, and a 0/0 budget would stall
+	// the rollout, so such a 0 still means 2.
+	if gsd.Spec.UpdateStrategy.MaxSurge == nil || isZeroBudget(gsd.Spec.UpdateStrategy.MaxSurge) &&
+		isZeroBudget(gsd.Spec.UpdateStrategy.MaxUnavailable) {
+		surge := intstr.FromInt32(2)
+		gsd.Spec.UpdateStrategy.MaxSurge = &surge
 	}
 
-	// Desired inline parameter (optional)
-	var desiredMaxPlayersStr string
-	if gsd.Spec.Parameters != nil && gsd.Spec.Parameters.MaxPlayers != nil {
-		desiredMaxPlayersStr = fmt.Sprintf("%d", *gsd.Spec.Parameters.MaxPlayers)
+	// Scheduled windows override the fleet's bounds while they are open.
+	activeSchedule, nextWindow, err := schedule.Resolve(gsd.Spec.Schedules, time.Now())
+	if err != nil {
+		log.Info("ignoring invalid schedules", "err", err.Error())
 	}
+	applySchedule(&gsd, activeSchedule)
+
+	// Desired inline parameter (optional)
+	desiredMaxPlayersStr := maxPlayersParam(&gsd)
 
 	// List children GameServers
 	var children gamev1alpha1.GameServerList
@@ -85,111 +125,277 @@ func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	used := map[int32]struct{}{}
-	ready := int32(0)
+	perPort := int32(1)
+	if gsd.Spec.PortPolicy == gamev1alpha1.PortPolicyPerNode {
+		n, err := eligibleNodes(ctx, r.Client, &gsd)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		perPort = n
+	}
+	// Servers that reached Shutdown (their Pod exited) are done: remove them
+	// so their ports and slots go to replacements.
 	for _, gs := range children.Items {
-		used[gs.Spec.Port] = struct{}{}
-		if gs.Status.Phase == "Running" {
-			ready++
+		if gs.Status.State != gamev1alpha1.GameServerStateShutdown {
+			continue
+		}
+		if err := r.Delete(ctx, &gs); err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
 		}
+		log.Info("removed shut down GameServer", "gameserver", gs.Name)
+		children.Items = removeGS(children.Items, gs.Name)
 	}
 
-	// Classify children: "desired" (matches image + MAX_PLAYERS) vs "outdated".
-	var outdated []gamev1alpha1.GameServer
-	var desiredOnes []gamev1alpha1.GameServer
+	used := newPortPool(gsd.Spec.PortRange, perPort)
 	for _, gs := range children.Items {
-		matchesImage := (gs.Spec.Image == gsd.Spec.Image)
-		matchesMP := (desiredMaxPlayersStr == "" || envHas(gs.Spec.Env, "MAX_PLAYERS", desiredMaxPlayersStr))
-		if matchesImage && matchesMP {
-			desiredOnes = append(desiredOnes, gs)
-		} else {
-			outdated = append(outdated, gs)
+		used.take(gs.Spec.Port)
+	}
+
+	// Every spec change is a new template revision with its own GameServerSet;
+	// servers of any other revision are outdated and rolled.
+	set, err := r.ensureSet(ctx, &gsd, childSpec(&gsd, desiredMaxPlayersStr))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// Paused: the revision is recorded, but every server stays as it is.
+	paused := gsd.Spec.Paused
+	var canary *canaryPlan
+	var blueGreen *blueGreenPlan
+	if !paused {
+		// Canary: part of the fleet stays on the stable revision, step by step.
+		if canary, err = r.planCanary(ctx, &gsd, set, children.Items); err != nil {
+			return ctrl.Result{}, err
+		}
+		// BlueGreen: the active fleet serves until a full preview is Ready.
+		if blueGreen, err = r.planBlueGreen(ctx, &gsd, set, children.Items); err != nil {
+			return ctrl.Result{}, err
 		}
 	}
+	var outdated, desiredOnes, stableOnes []gamev1alpha1.GameServer
+	for i := range children.Items {
+		gs := &children.Items[i]
+		if gs.Labels[gamev1alpha1.RevisionLabel] == "" && specRevision(gs.Spec) == set.Spec.Revision {
+			// Created before GameServerSets, but current: adopt it.
+			if err := r.adopt(ctx, set, gs); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		switch {
+		case gs.Labels[gamev1alpha1.RevisionLabel] == set.Spec.Revision:
+			desiredOnes = append(desiredOnes, *gs)
+		case paused || canary.holds(gs) || blueGreen.holds(gs):
+			stableOnes = append(stableOnes, *gs)
+		default:
+			outdated = append(outdated, *gs)
+		}
+	}
+	outdated = append(outdated, canary.shift(stableOnes)...)
 
 	// Mark outdated as draining so allocator avoids them
+	var newlyDrained, drainedPlayers int32
 	for i := range outdated {
 		anno := outdated[i].GetAnnotations()
 		if anno == nil {
 			anno = map[string]string{}
 		}
-		if anno[drainAnno] != "true" {
+		if anno[drainAnno] != "true" || anno[drainSinceAnno] == "" {
+			started := anno[drainAnno] != "true"
 			anno[drainAnno] = "true"
+			if anno[drainSinceAnno] == "" {
+				anno[drainSinceAnno] = time.Now().UTC().Format(time.RFC3339)
+			}
 			outdated[i].SetAnnotations(anno)
-			_ = r.Update(ctx, &outdated[i]) // best-effort
+			if err := r.Update(ctx, &outdated[i]); err != nil || !started { // best-effort
+				continue
+			}
+			r.eventf(&outdated[i], corev1.EventTypeNormal, "Draining",
+				"Draining for revision %s with %d players", set.Spec.Revision, outdated[i].Status.Players)
+			newlyDrained++
+			drainedPlayers += outdated[i].Status.Players
 		}
 	}
+	if newlyDrained > 0 {
+		r.eventf(&gsd, corev1.EventTypeNormal, "Draining",
+			"Draining %d outdated GameServers with %d players for revision %s (%d outdated in all)",
+			newlyDrained, drainedPlayers, set.Spec.Revision, len(outdated))
+	}
 
-	// Surge: if we have outdated servers, create up to MaxSurge new desired ones
+	// Drain timeout: an outdated server still busy after DrainTimeoutSeconds is
+	// force-terminated so one AFK player can't block the rollout forever.
+	drainTimeout := time.Duration(gsd.Spec.UpdateStrategy.DrainTimeoutSeconds) * time.Second
+	var forceDrained []string
+	var nextDrainDeadline time.Duration
+	stillOutdated := outdated[:0]
+	for _, gs := range outdated {
+		left, ok := drainTimeLeft(&gs, drainTimeout, time.Now())
+		if !ok || left > 0 || isReserved(&gs) {
+			// A reserved server is force-drained once the reservation lapses;
+			// its GameServer's status change brings us back then.
+			if ok && left > 0 && (nextDrainDeadline == 0 || left < nextDrainDeadline) {
+				nextDrainDeadline = left
+			}
+			stillOutdated = append(stillOutdated, gs)
+			continue
+		}
+		if err := r.Delete(ctx, &gs); err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		log.Info("force-drained", "gameserver", gs.Name, "players", gs.Status.Players)
+		r.eventf(&gsd, corev1.EventTypeWarning, "ForceDrained",
+			"Deleted outdated GameServer %s with %d players after drain timeout of %ds",
+			gs.Name, gs.Status.Players, gsd.Spec.UpdateStrategy.DrainTimeoutSeconds)
+		r.eventf(&gs, corev1.EventTypeWarning, "ForceDrained",
+			"Drain timeout of %ds exceeded with %d players", gsd.Spec.UpdateStrategy.DrainTimeoutSeconds, gs.Status.Players)
+		scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+		forceDrained = append(forceDrained, gs.Name)
+		children.Items = removeGS(children.Items, gs.Name)
+	}
+	outdated = stillOutdated
+
+	// Rollout budget (like apps/v1 Deployments). The fleet's target size is what
+	// we have minus the new servers already surged in; we may run up to MaxSurge
+	// above it and never let Running servers drop below target - MaxUnavailable.
 	total := int32(len(children.Items))
-	surgeLimit := total + gsd.Spec.UpdateStrategy.MaxSurge
-	for (len(outdated) > 0) && (total < surgeLimit) && (total < gsd.Spec.MaxReplicas) {
-		port, ok := allocatePort(used, gsd.Spec.PortRange.Start, gsd.Spec.PortRange.End)
-		if !ok {
+	maxSurge, maxUnavailable := resolveRolloutBudget(&gsd.Spec.UpdateStrategy, total)
+	rolloutTarget := total - minInt32(int32(len(desiredOnes)), maxSurge)
+	minReady := rolloutTarget - maxUnavailable
+	readyNow := int32(0)
+	for _, gs := range children.Items {
+		if isReady(&gs) {
+			readyNow++
+		}
+	}
+	canTakeDown := func(gs *gamev1alpha1.GameServer) bool {
+		return !isReady(gs) || readyNow-1 >= minReady
+	}
+
+	// Recreate and RollingUpdate do not wait for players: outdated servers are
+	// evicted all at once, or as far as MaxUnavailable allows while the surge
+	// below replaces them batch by batch.
+	switch gsd.Spec.UpdateStrategy.Type {
+	case gamev1alpha1.UpdateStrategyRecreate:
+		for _, gs := range outdated {
+			if err := r.evict(ctx, &gsd, &gs); err != nil {
+				return ctrl.Result{}, err
+			}
+			children.Items = removeGS(children.Items, gs.Name)
+			total--
+		}
+		outdated = nil
+	case gamev1alpha1.UpdateStrategyRollingUpdate:
+		stillOutdated := outdated[:0]
+		for _, gs := range outdated {
+			if !canTakeDown(&gs) {
+				stillOutdated = append(stillOutdated, gs)
+				continue
+			}
+			if err := r.evict(ctx, &gsd, &gs); err != nil {
+				return ctrl.Result{}, err
+			}
+			if isReady(&gs) {
+				readyNow--
+			}
+			children.Items = removeGS(children.Items, gs.Name)
+			total--
+		}
+		outdated = stillOutdated
+	}
+
+	// No room to surge (fleet at maxReplicas): take idle outdated servers out of
+	// service, within MaxUnavailable, so replacements can be created below.
+	if len(outdated) > 0 && total >= gsd.Spec.MaxReplicas {
+		stillOutdated := outdated[:0]
+		from := total
+		for _, gs := range outdated {
+			if !isEmpty(&gs) || isAllocated(&gs) || isReserved(&gs) || !canTakeDown(&gs) {
+				stillOutdated = append(stillOutdated, gs)
+				continue
+			}
+			if err := r.Delete(ctx, &gs); err != nil && !kerrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			if isReady(&gs) {
+				readyNow--
+			}
+			scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+			children.Items = removeGS(children.Items, gs.Name)
+			total--
+		}
+		outdated = stillOutdated
+		if total < from {
+			r.eventf(&gsd, corev1.EventTypeNormal, "ScalingDown",
+				"Scaled down from %d to %d GameServers: no room to surge at maxReplicas %d, deleted idle outdated servers",
+				from, total, gsd.Spec.MaxReplicas)
+		}
+	}
+
+	// Surge: if we have outdated servers, create up to MaxSurge new desired ones
+	surgeLimit := rolloutTarget + maxSurge
+	surged := int32(0)
+	for (len(outdated) > 0) && (total < surgeLimit) && (total < gsd.Spec.MaxReplicas) && canary.wantsMore() && blueGreen.surges() {
+		newGS, err := r.createChild(ctx, &gsd, set, used)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if newGS == nil {
 			break
 		}
-		name := fmt.Sprintf("%s-%d", gsd.Name, port)
-		newGS := gamev1alpha1.GameServer{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:        name,
-				Namespace:   gsd.Namespace,
-				Labels:      childLabels(gsd.Name),
-				Annotations: map[string]string{},
-			},
-			Spec: gamev1alpha1.GameServerSpec{
-				Image:        gsd.Spec.Image,
-				Port:         port,
-				PollPath:     gsd.Spec.PollPath,
-				Env:          ensureMaxPlayers(gsd.Spec.Env, desiredMaxPlayersStr),
-				Resources:    gsd.Spec.Resources,
-				NodeSelector: gsd.Spec.NodeSelector,
-			},
-		}
-		if err := ctrl.SetControllerReference(&gsd, &newGS, r.Scheme); err != nil {
+		total++
+		surged++
+		canary.added()
+		desiredOnes = append(desiredOnes, *newGS)
+		children.Items = append(children.Items, *newGS)
+	}
+	if surged > 0 {
+		r.eventf(&gsd, corev1.EventTypeNormal, "ScalingUp",
+			"Scaled up from %d to %d GameServers: surged on revision %s to replace %d outdated (maxSurge %d)",
+			total-surged, total, set.Spec.Revision, len(outdated), maxSurge)
+	}
+	// BlueGreen: bring the preview up to the active fleet's Ready count in one go.
+	previewed := int32(0)
+	for n := blueGreen.missing(); n > 0; n-- {
+		newGS, err := r.createChild(ctx, &gsd, set, used)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
-		if err := r.Create(ctx, &newGS); err != nil {
+		if newGS == nil {
+			break
+		}
+		previewed++
+		desiredOnes = append(desiredOnes, *newGS)
+		children.Items = append(children.Items, *newGS)
+	}
+	if previewed > 0 {
+		r.eventf(&gsd, corev1.EventTypeNormal, "ScalingUp",
+			"Created %d preview GameServers on revision %s: %d of %d wanted",
+			previewed, set.Spec.Revision, blueGreen.green+previewed, blueGreen.target)
+	}
+	growSet := blueGreen.growSet(canary.growSet(set))
+	if paused {
+		if growSet, err = r.servingSet(ctx, &gsd, set, children.Items); err != nil {
 			return ctrl.Result{}, err
 		}
-		used[port] = struct{}{}
-		total++
-		desiredOnes = append(desiredOnes, newGS)
 	}
 
 	// Ensure minReplicas
 	desired := maxInt32(gsd.Spec.MinReplicas, 0)
 	cur := int32(len(children.Items))
 
+	from := cur
 	for cur < desired {
-		port, ok := allocatePort(used, gsd.Spec.PortRange.Start, gsd.Spec.PortRange.End)
-		if !ok {
-			break
-		}
-		name := fmt.Sprintf("%s-%d", gsd.Name, port)
-		newGS := gamev1alpha1.GameServer{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: gsd.Namespace,
-				Labels:    childLabels(gsd.Name),
-			},
-			Spec: gamev1alpha1.GameServerSpec{
-				Image:        gsd.Spec.Image,
-				Port:         port,
-				PollPath:     gsd.Spec.PollPath,
-				Env:          ensureMaxPlayers(gsd.Spec.Env, desiredMaxPlayersStr),
-				Resources:    gsd.Spec.Resources,
-				NodeSelector: gsd.Spec.NodeSelector,
-			},
-		}
-		if err := ctrl.SetControllerReference(&gsd, &newGS, r.Scheme); err != nil {
+		newGS, err := r.createChild(ctx, &gsd, growSet, used)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
-		if err := r.Create(ctx, &newGS); err != nil {
-			return ctrl.Result{}, err
+		if newGS == nil {
+			break
 		}
-		used[port] = struct{}{}
 		cur++
 	}
+	if cur > from {
+		r.eventf(&gsd, corev1.EventTypeNormal, "ScalingUp",
+			"Scaled up from %d to %d GameServers: below minReplicas %d", from, cur, desired)
+	}
 
 	// Re-list after potential creates
 	if err := r.List(ctx, &children, client.InNamespace(gsd.Namespace),
@@ -197,84 +403,201 @@ func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	// Scale Up rule: ANY >= threshold
-	scaleUp := false
-	for _, gs := range children.Items {
-		mp := gs.Status.MaxPlayers
-		if mp > 0 && gs.Status.Players*100/mp >= gsd.Spec.ScaleUpThresholdPercent {
-			scaleUp = true
-			break
-		}
-	}
-	if scaleUp && int32(len(children.Items)) < gsd.Spec.MaxReplicas {
-		port, ok := allocatePort(used, gsd.Spec.PortRange.Start, gsd.Spec.PortRange.End)
-		if ok {
-			name := fmt.Sprintf("%s-%d", gsd.Name, port)
-			newGS := gamev1alpha1.GameServer{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      name,
-					Namespace: gsd.Namespace,
-					Labels:    childLabels(gsd.Name),
-				},
-				Spec: gamev1alpha1.GameServerSpec{
-					Image:        gsd.Spec.Image,
-					Port:         port,
-					PollPath:     gsd.Spec.PollPath,
-					Env:          ensureMaxPlayers(gsd.Spec.Env, desiredMaxPlayersStr),
-					Resources:    gsd.Spec.Resources,
-					NodeSelector: gsd.Spec.NodeSelector,
-				},
+	// Each policy sizes the current (non-draining) servers and reports its
+	// target. A BlueGreen preview is not part of what they size.
+	var preview []gamev1alpha1.GameServer
+	children.Items, preview = blueGreen.hide(children.Items)
+	var desiredReplicas int32
+	var webhookErr error
+	switch policy := scalingPolicy(&gsd); {
+	case gsd.Spec.AutoscalingPaused:
+		// Frozen: the fleet keeps the size it has.
+		for _, gs := range children.Items {
+			if !isDraining(&gs) {
+				desiredReplicas++
 			}
-			_ = ctrl.SetControllerReference(&gsd, &newGS, r.Scheme)
-			if err := r.Create(ctx, &newGS); err == nil {
-				used[port] = struct{}{}
-				children.Items = append(children.Items, newGS)
+		}
+	case policy == gamev1alpha1.ScalingPolicyWebhook:
+		target, err := r.webhookTarget(ctx, &gsd, children.Items)
+		if err != nil {
+			// Keep the fleet responsive with the built-in rule until the webhook is back.
+			log.Info("scaling webhook unavailable, falling back to threshold", "err", err.Error())
+			webhookErr = err
+			if children.Items, desiredReplicas, err = r.scaleThreshold(ctx, &gsd, children.Items, used, growSet); err != nil {
+				return ctrl.Result{}, err
 			}
+		} else if children.Items, desiredReplicas, err = r.scaleTo(ctx, &gsd, children.Items, target,
+			fmt.Sprintf("scaling webhook asked for %d", target), used, growSet); err != nil {
+			return ctrl.Result{}, err
+		}
+	case policy == gamev1alpha1.ScalingPolicyBuffer:
+		// Buffer: create/remove in one go to keep N empty servers ready.
+		var err error
+		if children.Items, desiredReplicas, err = r.scaleBuffer(ctx, &gsd, children.Items, used, growSet); err != nil {
+			return ctrl.Result{}, err
+		}
+	case policy == gamev1alpha1.ScalingPolicyUtilization:
+		var err error
+		if children.Items, desiredReplicas, err = r.scaleUtilization(ctx, &gsd, children.Items, used, growSet); err != nil {
+			return ctrl.Result{}, err
+		}
+	default:
+		var err error
+		if children.Items, desiredReplicas, err = r.scaleThreshold(ctx, &gsd, children.Items, used, growSet); err != nil {
+			return ctrl.Result{}, err
 		}
 	}
 
-	// Scale Down rule:
-	//  - If draining and idle (players==0) → delete immediately.
-	//  - Else (not draining) → delete only if idle for > scaleDownZeroSeconds.
-	if int32(len(children.Items)) > gsd.Spec.MinReplicas {
+	children.Items = append(children.Items, preview...)
+
+	// Scale Down rule for the rollout (the policies above size the current servers):
+	//  - Allocated servers are never touched; the match owns them. Reserved
+	//    ones wait for their reservation to lapse.
+	//  - If draining and idle (players==0) → delete immediately, within MaxUnavailable.
+	if !paused && int32(len(children.Items)) > gsd.Spec.MinReplicas {
 		var idle []gamev1alpha1.GameServer
-		now := time.Now()
 		for _, gs := range children.Items {
-			anno := gs.GetAnnotations()
-			isDraining := (anno != nil && anno[drainAnno] == "true")
-			if gs.Status.Players == 0 {
-				if isDraining {
-					idle = append(idle, gs)
-				} else if gs.Status.ZeroSince != nil {
-					if now.Sub(gs.Status.ZeroSince.Time) >= time.Duration(gsd.Spec.ScaleDownZeroSeconds)*time.Second {
-						idle = append(idle, gs)
-					}
-				}
+			if isAllocated(&gs) || isReserved(&gs) || !isEmpty(&gs) || !isDraining(&gs) {
+				continue
+			}
+			// Outdated: replaced by the rollout, within MaxUnavailable.
+			if !canTakeDown(&gs) {
+				continue
+			}
+			if isReady(&gs) {
+				readyNow--
 			}
+			idle = append(idle, gs)
 		}
 		// Delete oldest idle first (by creation timestamp)
 		sort.Slice(idle, func(i, j int) bool {
 			return idle[i].CreationTimestamp.Before(&idle[j].CreationTimestamp)
 		})
+		from := int32(len(children.Items))
 		for _, gs := range idle {
 			if int32(len(children.Items)) <= gsd.Spec.MinReplicas {
 				break
 			}
-			_ = r.Delete(ctx, &gs)
+			if err := r.Delete(ctx, &gs); err == nil {
+				scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+			}
 			// pessimistically reduce count so we don't over-delete in this loop
 			children.Items = removeGS(children.Items, gs.Name)
 		}
+		if to := int32(len(children.Items)); to < from {
+			r.eventf(&gsd, corev1.EventTypeNormal, "ScalingDown",
+				"Scaled down from %d to %d GameServers: deleted empty draining servers (minReplicas %d)",
+				from, to, gsd.Spec.MinReplicas)
+		}
+	}
+
+	// Sets whose servers are all gone are history, up to revisionHistoryLimit.
+	sets, err := r.pruneSets(ctx, &gsd, set, children.Items)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// Update status
 	alloc := make([]int32, 0, len(children.Items))
+	var ready, draining, players, capacity int32
 	for _, gs := range children.Items {
 		alloc = append(alloc, gs.Spec.Port)
+		if isReady(&gs) {
+			ready++
+		}
+		if isDraining(&gs) {
+			draining++
+		}
+		players += gs.Status.Players
+		capacity += gs.Status.MaxPlayers
 	}
-	newStatus := gsd.Status
+	fleetReplicas.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(len(children.Items)))
+	fleetDesiredReplicas.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(desiredReplicas))
+	fleetReadyReplicas.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(ready))
+	fleetDrainingReplicas.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(draining))
+	fleetPlayers.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(players))
+	fleetCapacity.WithLabelValues(gsd.Namespace, gsd.Name).Set(float64(capacity))
+	newStatus := *gsd.Status.DeepCopy()
 	newStatus.Replicas = int32(len(children.Items))
 	newStatus.ReadyReplicas = ready
+	newStatus.DesiredReplicas = desiredReplicas
 	newStatus.AllocatedPorts = alloc
+	newStatus.NodePortUsage = nodePortUsage(children.Items)
+	newStatus.ObservedGeneration = gsd.Generation
+	newStatus.UpdateRevision = set.Spec.Revision
+	newStatus.RevisionHistory = revisionHistory(sets, children.Items)
+	if allRevision(children.Items, set.Spec.Revision) {
+		newStatus.CurrentRevision = set.Spec.Revision
+	} else if canary != nil && canary.stable != nil {
+		newStatus.CurrentRevision = canary.stable.Spec.Revision
+	}
+	newStatus.UpdatedReplicas, newStatus.DrainingReplicas, newStatus.AvailableReplicas = 0, draining, 0
+	var unhealthy int32
+	for _, gs := range children.Items {
+		if gs.Status.State == gamev1alpha1.GameServerStateUnhealthy {
+			unhealthy++
+		}
+		if gs.Labels[gamev1alpha1.RevisionLabel] == set.Spec.Revision {
+			newStatus.UpdatedReplicas++
+		}
+		if isReady(&gs) && !isDraining(&gs) {
+			newStatus.AvailableReplicas++
+		}
+	}
+	if !paused {
+		// A paused rollout keeps reporting where it stopped.
+		newStatus.Canary = nil
+		if canary != nil {
+			newStatus.Canary = &canary.status
+		}
+		newStatus.BlueGreen = nil
+		if blueGreen != nil {
+			newStatus.BlueGreen = &blueGreen.status
+		}
+	}
+	newStatus.ActiveSchedule = ""
+	if activeSchedule != nil {
+		newStatus.ActiveSchedule = activeSchedule.Name
+	}
+	if len(forceDrained) > 0 {
+		meta.SetStatusCondition(&newStatus.Conditions, metav1.Condition{
+			Type:               condForceDrained,
+			Status:             metav1.ConditionTrue,
+			Reason:             "DrainTimeoutExceeded",
+			Message:            fmt.Sprintf("Force-drained after %ds: %s", gsd.Spec.UpdateStrategy.DrainTimeoutSeconds, strings.Join(forceDrained, ", ")),
+			ObservedGeneration: gsd.Generation,
+		})
+	} else if !paused && len(outdated) == 0 && meta.IsStatusConditionTrue(newStatus.Conditions, condForceDrained) {
+		meta.SetStatusCondition(&newStatus.Conditions, metav1.Condition{
+			Type:               condForceDrained,
+			Status:             metav1.ConditionFalse,
+			Reason:             "RolloutComplete",
+			Message:            "No outdated GameServers left",
+			ObservedGeneration: gsd.Generation,
+		})
+	}
+	switch {
+	case scalingPolicy(&gsd) != gamev1alpha1.ScalingPolicyWebhook:
+		meta.RemoveStatusCondition(&newStatus.Conditions, condScalingFallback)
+	case webhookErr != nil:
+		meta.SetStatusCondition(&newStatus.Conditions, metav1.Condition{
+			Type:               condScalingFallback,
+			Status:             metav1.ConditionTrue,
+			Reason:             "WebhookUnavailable",
+			Message:            fmt.Sprintf("Using the threshold rule: %v", webhookErr),
+			ObservedGeneration: gsd.Generation,
+		})
+	default:
+		meta.SetStatusCondition(&newStatus.Conditions, metav1.Condition{
+			Type:               condScalingFallback,
+			Status:             metav1.ConditionFalse,
+			Reason:             "WebhookOK",
+			Message:            "Replica count comes from the scaling webhook",
+			ObservedGeneration: gsd.Generation,
+		})
+	}
+	setPausedConditions(&newStatus, &gsd)
+	setRolloutConditions(&newStatus, &gsd, maxUnavailable, unhealthy, used.exhausted)
 	if !equality.Semantic.DeepEqual(newStatus, gsd.Status) {
 		gsd.Status = newStatus
 		if err := r.Status().Update(ctx, &gsd); err != nil && !kerrors.IsNotFound(err) {
@@ -282,10 +605,32 @@ func (r *GSDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// Come back when the next draining server hits its timeout, a canary pause
+	// ends, a schedule window opens or closes or a scale change held back by
+	// scaling.behavior may go ahead, and ask the scaling webhook again even if
+	// nothing in the fleet changes.
+	requeue := nextDrainDeadline
+	if canary != nil && canary.requeue > 0 && (requeue == 0 || canary.requeue < requeue) {
+		requeue = canary.requeue
+	}
+	if d := r.behaviorRequeue(&gsd); d > 0 && (requeue == 0 || d < requeue) {
+		requeue = d
+	}
+	if !nextWindow.IsZero() {
+		if d := time.Until(nextWindow); requeue == 0 || d < requeue {
+			requeue = max(d, time.Second)
+		}
+	}
+	if scalingPolicy(&gsd) == gamev1alpha1.ScalingPolicyWebhook && (requeue == 0 || requeue > webhookResync) {
+		requeue = webhookResync
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
 }
 
 func (r *GSDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Built once, before any worker runs: reconciles share its HTTP clients.
+	r.scaleHooks = &autoscaler.Client{}
+
 	// React immediately to GameServer STATUS updates
 	statusChanged := predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
@@ -297,23 +642,245 @@ func (r *GSDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			return !equality.Semantic.DeepEqual(oldObj.Status, newObj.Status)
 		},
 	}
+	// GameServers belong to a GameServerSet; find the fleet by owner label.
+	fleetOf := handler.EnqueueRequestsFromMapFunc(func(_ context.Context, obj client.Object) []reconcile.Request {
+		owner := obj.GetLabels()["game.example.com/owner"]
+		if owner == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner}}}
+	})
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&gamev1alpha1.GSDeployment{}).
-		Owns(&gamev1alpha1.GameServer{}, builder.WithPredicates(statusChanged)).
+		Owns(&gamev1alpha1.GameServerSet{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&gamev1alpha1.GameServer{}, fleetOf, builder.WithPredicates(statusChanged)).
 		Complete(r)
 }
 
+// createChild allocates a port and creates one new GameServer in set, the
+// current revision. It returns nil (and no error) when the port range is exhausted.
+func (r *GSDeploymentReconciler) createChild(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	set *gamev1alpha1.GameServerSet, used *portPool) (*gamev1alpha1.GameServer, error) {
+	warned := used.exhausted
+	port, ok := used.allocate()
+	if !ok {
+		portRangeExhausted.WithLabelValues(gsd.Namespace, gsd.Name).Inc()
+		if !warned {
+			r.eventf(gsd, corev1.EventTypeWarning, "PortRangeExhausted",
+				"No free port in %d-%d for a new GameServer: %d servers on %d ports, %d per port",
+				used.start, used.end, used.servers(), len(used.used), used.perPort)
+		}
+		return nil, nil
+	}
+	newGS := gamev1alpha1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", gsd.Name, port),
+			Namespace: gsd.Namespace,
+			Labels:    setLabels(gsd.Name, set.Spec.Revision),
+		},
+		Spec: *set.Spec.Template.DeepCopy(),
+	}
+	newGS.Spec.Port = port
+	if used.perPort > 1 {
+		// The same port lives on several nodes; let the API server pick a unique name.
+		newGS.Name = ""
+		newGS.GenerateName = fmt.Sprintf("%s-%d-", gsd.Name, port)
+	}
+	if err := ctrl.SetControllerReference(set, &newGS, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, &newGS); err != nil {
+		return nil, err
+	}
+	scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "up").Inc()
+	return &newGS, nil
+}
+
+// defaultTemplate fills in the server template fields Reconcile defaults.
+func defaultTemplate(spec *gamev1alpha1.GSDeploymentSpec) {
+	if spec.PollPath == "" {
+		spec.PollPath = "/status"
+	}
+	// With a template the image normally lives in the game container.
+	if spec.Image == "" && spec.Template == nil {
+		spec.Image = "kyon/gameserver:latest"
+	}
+}
+
+// maxPlayersParam is parameters.maxPlayers as a MAX_PLAYERS value, or "".
+func maxPlayersParam(gsd *gamev1alpha1.GSDeployment) string {
+	if gsd.Spec.Parameters != nil && gsd.Spec.Parameters.MaxPlayers != nil {
+		return fmt.Sprintf("%d", *gsd.Spec.Parameters.MaxPlayers)
+	}
+	return ""
+}
+
+// childSpec is the GameServerSpec template of the fleet's servers; each
+// server gets its own port on top.
+func childSpec(gsd *gamev1alpha1.GSDeployment, maxPlayers string) gamev1alpha1.GameServerSpec {
+	return gamev1alpha1.GameServerSpec{
+		Image:                   gsd.Spec.Image,
+		PollPath:                gsd.Spec.PollPath,
+		Env:                     ensureMaxPlayers(gsd.Spec.Env, maxPlayers),
+		Resources:               gsd.Spec.Resources,
+		NodeSelector:            gsd.Spec.NodeSelector,
+		Template:                gsd.Spec.Template.DeepCopy(),
+		Container:               gsd.Spec.Container,
+		StatusProbe:             gsd.Spec.StatusProbe.DeepCopy(),
+		StatusMode:              gsd.Spec.StatusMode,
+		HeartbeatTimeoutSeconds: gsd.Spec.HeartbeatTimeoutSeconds,
+	}
+}
+
 func childLabels(owner string) map[string]string {
 	return map[string]string{"game.example.com/owner": owner}
 }
 
-func allocatePort(used map[int32]struct{}, start, end int32) (int32, bool) {
-	for p := start; p <= end; p++ {
-		if _, ok := used[p]; !ok {
-			return p, true
+// drainTimeLeft reports how long a draining server may keep running.
+// ok is false when the server is not draining or has no valid start time.
+func drainTimeLeft(gs *gamev1alpha1.GameServer, timeout time.Duration, now time.Time) (left time.Duration, ok bool) {
+	if !isDraining(gs) {
+		return 0, false
+	}
+	since, err := time.Parse(time.RFC3339, gs.GetAnnotations()[drainSinceAnno])
+	if err != nil {
+		return 0, false
+	}
+	return since.Add(timeout).Sub(now), true
+}
+
+// setPausedConditions reports spec.paused and spec.autoscalingPaused.
+func setPausedConditions(st *gamev1alpha1.GSDeploymentStatus, gsd *gamev1alpha1.GSDeployment) {
+	rollout := metav1.Condition{Type: condPaused, Status: metav1.ConditionFalse, Reason: "RolloutResumed",
+		Message: "Outdated GameServers are rolled", ObservedGeneration: gsd.Generation}
+	if gsd.Spec.Paused {
+		rollout.Status, rollout.Reason = metav1.ConditionTrue, "RolloutPaused"
+		rollout.Message = "spec.paused: outdated GameServers are neither drained nor replaced"
+	}
+	meta.SetStatusCondition(&st.Conditions, rollout)
+	scaling := metav1.Condition{Type: condScalingPaused, Status: metav1.ConditionFalse, Reason: "AutoscalingResumed",
+		Message: "The scaling policy sizes the fleet", ObservedGeneration: gsd.Generation}
+	if gsd.Spec.AutoscalingPaused {
+		scaling.Status, scaling.Reason = metav1.ConditionTrue, "AutoscalingPaused"
+		scaling.Message = "spec.autoscalingPaused: the scaling policy neither adds nor removes GameServers"
+	}
+	meta.SetStatusCondition(&st.Conditions, scaling)
+}
+
+// setRolloutConditions maintains the Deployment-style conditions from the
+// counts in st:
+//   - Available: at least minReplicas - maxUnavailable servers are available.
+//   - Progressing: True while a rollout runs and once it completed
+//     (reason NewRevisionAvailable), Unknown while paused.
+//   - ReplicaFailure: a server could not be created or is Unhealthy.
+func setRolloutConditions(st *gamev1alpha1.GSDeploymentStatus, gsd *gamev1alpha1.GSDeployment,
+	maxUnavailable, unhealthy int32, portsExhausted bool) {
+	available := metav1.Condition{Type: condAvailable, Status: metav1.ConditionTrue, Reason: "MinimumReplicasAvailable",
+		Message: fmt.Sprintf("%d GameServers available", st.AvailableReplicas), ObservedGeneration: gsd.Generation}
+	if need := gsd.Spec.MinReplicas - maxUnavailable; st.AvailableReplicas < need {
+		available.Status, available.Reason = metav1.ConditionFalse, "MinimumReplicasUnavailable"
+		available.Message = fmt.Sprintf("%d GameServers available, %d required", st.AvailableReplicas, need)
+	}
+	meta.SetStatusCondition(&st.Conditions, available)
+
+	progressing := metav1.Condition{Type: condProgressing, Status: metav1.ConditionTrue, ObservedGeneration: gsd.Generation}
+	switch {
+	case gsd.Spec.Paused:
+		progressing.Status, progressing.Reason = metav1.ConditionUnknown, "RolloutPaused"
+		progressing.Message = "The rollout is paused"
+	case st.UpdatedReplicas == st.Replicas && st.CurrentRevision == st.UpdateRevision:
+		progressing.Reason = "NewRevisionAvailable"
+		progressing.Message = fmt.Sprintf("All %d GameServers run revision %s", st.Replicas, st.UpdateRevision)
+	default:
+		progressing.Reason = "RolloutInProgress"
+		progressing.Message = fmt.Sprintf("%d of %d GameServers updated to revision %s, %d draining",
+			st.UpdatedReplicas, st.Replicas, st.UpdateRevision, st.DrainingReplicas)
+	}
+	meta.SetStatusCondition(&st.Conditions, progressing)
+
+	failure := metav1.Condition{Type: condReplicaFailure, Status: metav1.ConditionFalse, Reason: "NoFailure",
+		Message: "No GameServer failed", ObservedGeneration: gsd.Generation}
+	switch {
+	case portsExhausted:
+		failure.Status, failure.Reason = metav1.ConditionTrue, "PortRangeExhausted"
+		failure.Message = fmt.Sprintf("No free port in %d-%d for a new GameServer", gsd.Spec.PortRange.Start, gsd.Spec.PortRange.End)
+	case unhealthy > 0:
+		failure.Status, failure.Reason = metav1.ConditionTrue, "GameServersUnhealthy"
+		failure.Message = fmt.Sprintf("%d GameServers are Unhealthy", unhealthy)
+	}
+	meta.SetStatusCondition(&st.Conditions, failure)
+}
+
+// evict deletes an outdated server whatever its players, for the Recreate
+// and RollingUpdate strategies, and records it on both objects.
+func (r *GSDeploymentReconciler) evict(ctx context.Context, gsd *gamev1alpha1.GSDeployment, gs *gamev1alpha1.GameServer) error {
+	if err := r.Delete(ctx, gs); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	ctrl.Log.WithName("gsdeployment").Info("evicted", "gameserver", gs.Name, "players", gs.Status.Players,
+		"strategy", gsd.Spec.UpdateStrategy.Type)
+	r.eventf(gsd, corev1.EventTypeWarning, "Evicted", "%s: deleted outdated GameServer %s with %d players",
+		gsd.Spec.UpdateStrategy.Type, gs.Name, gs.Status.Players)
+	r.eventf(gs, corev1.EventTypeWarning, "Evicted", "Deleted by the %s update strategy with %d players",
+		gsd.Spec.UpdateStrategy.Type, gs.Status.Players)
+	scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+	return nil
+}
+
+// eventf records an Event when a recorder is wired (tests may leave it nil).
+func (r *GSDeploymentReconciler) eventf(obj runtime.Object, eventType, reason, format string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, eventType, reason, format, args...)
+	}
+}
+
+// isReady is true while a server is up and reporting in (Ready, Reserved or Allocated).
+func isReady(gs *gamev1alpha1.GameServer) bool {
+	return gs.Status.State.IsReady()
+}
+
+// isEmpty is true when a server reports no players. A server whose counts
+// are unknown (TCP probe) is never empty.
+func isEmpty(gs *gamev1alpha1.GameServer) bool {
+	return gs.Status.Players == 0 && !gs.Status.PlayersUnknown
+}
+
+func isDraining(gs *gamev1alpha1.GameServer) bool {
+	return gs.GetAnnotations()[drainAnno] == "true"
+}
+
+// isAllocated is true once an allocation has claimed the server; the
+// annotation is set before the state catches up.
+func isAllocated(gs *gamev1alpha1.GameServer) bool {
+	return gs.GetAnnotations()[allocatedAnno] != "" || gs.Status.State == gamev1alpha1.GameServerStateAllocated
+}
+
+// reservation reads a server's reservation annotations. ok is false when it
+// has none, the expiry is malformed, or the reservation lapsed by now.
+func reservation(gs *gamev1alpha1.GameServer, now time.Time) (by string, until time.Time, ok bool) {
+	anno := gs.GetAnnotations()
+	until, err := time.Parse(time.RFC3339, anno[gamev1alpha1.ReservedUntilAnnotation])
+	if err != nil || !now.Before(until) {
+		return "", time.Time{}, false
+	}
+	return anno[gamev1alpha1.ReservedByAnnotation], until, true
+}
+
+// isReserved is true while a reservation holds the server; scale-down and the
+// rollout leave it alone until then.
+func isReserved(gs *gamev1alpha1.GameServer) bool {
+	_, _, ok := reservation(gs, time.Now())
+	return ok
+}
+
+// allRevision reports whether every server in list runs revision.
+func allRevision(list []gamev1alpha1.GameServer, revision string) bool {
+	for _, gs := range list {
+		if gs.Labels[gamev1alpha1.RevisionLabel] != revision {
+			return false
 		}
 	}
-	return 0, false
+	return true
 }
 
 func removeGS(list []gamev1alpha1.GameServer, name string) []gamev1alpha1.GameServer {
@@ -326,23 +893,41 @@ func removeGS(list []gamev1alpha1.GameServer, name string) []gamev1alpha1.GameSe
 	return out
 }
 
-func maxInt32(a, b int32) int32 {
-	if a > b {
+func minInt32(a, b int32) int32 {
+	if a < b {
 		return a
 	}
 	return b
 }
 
-// helpers for MAX_PLAYERS env
-func envHas(env []corev1.EnvVar, name, want string) bool {
-	for _, e := range env {
-		if e.Name == name && e.Value == want {
-			return true
-		}
+// resolveRolloutBudget turns MaxSurge/MaxUnavailable into absolute numbers for a
+// fleet of the given size: surge rounds up, unavailable rounds down.
+func resolveRolloutBudget(us *gamev1alpha1.UpdateStrategy, fleet int32) (surge, unavailable int32) {
+	s, err := intstr.GetScaledValueFromIntOrPercent(us.MaxSurge, int(fleet), true)
+	if err != nil || s < 0 {
+		s = 0
+	}
+	u, err := intstr.GetScaledValueFromIntOrPercent(us.MaxUnavailable, int(fleet), false)
+	if err != nil || u < 0 {
+		u = 0
+	}
+	return int32(s), int32(u)
+}
+
+// isZeroBudget reports whether a rollout budget is 0, as a number or a percentage.
+func isZeroBudget(v *intstr.IntOrString) bool {
+	n, err := intstr.GetScaledValueFromIntOrPercent(v, 100, false)
+	return err == nil && n == 0
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
 	}
-	return false
+	return b
 }
 
+// helper for MAX_PLAYERS env
 func ensureMaxPlayers(env []corev1.EnvVar, mp string) []corev1.EnvVar {
 	if mp == "" {
 		return env
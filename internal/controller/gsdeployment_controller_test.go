@@ -18,16 +18,30 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/autoscaler"
 )
 
 var _ = Describe("GSDeployment Controller", func() {
@@ -82,3 +96,455 @@ var _ = Describe("GSDeployment Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("drainTimeLeft", func() {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	gs := func(anno map[string]string) *gamev1alpha1.GameServer {
+		return &gamev1alpha1.GameServer{ObjectMeta: metav1.ObjectMeta{Annotations: anno}}
+	}
+
+	It("ignores servers that are not draining", func() {
+		_, ok := drainTimeLeft(gs(nil), time.Hour, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("counts down from the draining-since timestamp", func() {
+		left, ok := drainTimeLeft(gs(map[string]string{
+			drainAnno:      "true",
+			drainSinceAnno: now.Add(-40 * time.Minute).Format(time.RFC3339),
+		}), time.Hour, now)
+		Expect(ok).To(BeTrue())
+		Expect(left).To(Equal(20 * time.Minute))
+	})
+
+	It("goes negative once the timeout has passed", func() {
+		left, ok := drainTimeLeft(gs(map[string]string{
+			drainAnno:      "true",
+			drainSinceAnno: now.Add(-2 * time.Hour).Format(time.RFC3339),
+		}), time.Hour, now)
+		Expect(ok).To(BeTrue())
+		Expect(left).To(BeNumerically("<", 0))
+	})
+})
+
+var _ = Describe("resolveRolloutBudget", func() {
+	It("accepts absolute numbers", func() {
+		surge, unavailable := intstr.FromInt32(3), intstr.FromInt32(1)
+		s, u := resolveRolloutBudget(&gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: &unavailable}, 10)
+		Expect(s).To(Equal(int32(3)))
+		Expect(u).To(Equal(int32(1)))
+	})
+
+	It("rounds surge up and unavailable down for percentages", func() {
+		pct := intstr.FromString("25%")
+		s, u := resolveRolloutBudget(&gamev1alpha1.UpdateStrategy{MaxSurge: &pct, MaxUnavailable: &pct}, 10)
+		Expect(s).To(Equal(int32(3)))
+		Expect(u).To(Equal(int32(2)))
+	})
+})
+
+var _ = Describe("rolling a fleet at maxReplicas", func() {
+	ctx := context.Background()
+
+	DescribeTable("takes idle outdated servers down within maxUnavailable",
+		func(maxUnavailable int32, deleted int) {
+			servers := make([]gamev1alpha1.GameServer, 4)
+			for i := range servers {
+				servers[i] = fleetServer(i, gamev1alpha1.GameServerStateReady, 0)
+				servers[i].Labels[gamev1alpha1.RevisionLabel] = "old"
+			}
+			r, c := newScalingReconciler(servers)
+			surge, unavailable := intstr.FromInt32(1), intstr.FromInt32(maxUnavailable)
+			gsd := bufferFleet(intstr.FromInt32(0), 4, 4)
+			gsd.Spec.Scaling = nil
+			gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: &unavailable}
+			Expect(c.Create(ctx, gsd)).To(Succeed())
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+			Expect(err).NotTo(HaveOccurred())
+			var left gamev1alpha1.GameServerList
+			Expect(c.List(ctx, &left)).To(Succeed())
+			old := 0
+			for _, gs := range left.Items {
+				if gs.Labels[gamev1alpha1.RevisionLabel] == "old" {
+					old++
+				}
+			}
+			Expect(old).To(Equal(4 - deleted))
+			Expect(left.Items).To(HaveLen(4)) // a replacement takes each freed slot
+		},
+		Entry("one at a time with maxUnavailable 1", int32(1), 1),
+		Entry("none with maxUnavailable 0", int32(0), 0),
+	)
+})
+
+var _ = Describe("a stored maxSurge of 0", func() {
+	ctx := context.Background()
+
+	DescribeTable("surges as before unless maxUnavailable allows progress",
+		func(unavailable *intstr.IntOrString, want int) {
+			servers := make([]gamev1alpha1.GameServer, 4)
+			for i := range servers {
+				servers[i] = fleetServer(i, gamev1alpha1.GameServerStateReady, 3)
+				servers[i].Labels[gamev1alpha1.RevisionLabel] = "old"
+			}
+			r, c := newScalingReconciler(servers)
+			surge := intstr.FromInt32(0)
+			gsd := bufferFleet(intstr.FromInt32(0), 4, 10)
+			gsd.Spec.Scaling = nil
+			gsd.Spec.ScaleUpThresholdPercent = 80
+			gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: unavailable}
+			Expect(c.Create(ctx, gsd)).To(Succeed())
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+			Expect(err).NotTo(HaveOccurred())
+			var left gamev1alpha1.GameServerList
+			Expect(c.List(ctx, &left)).To(Succeed())
+			Expect(left.Items).To(HaveLen(want))
+		},
+		Entry("means 2 without maxUnavailable", nil, 6),
+		Entry("means 2 with maxUnavailable 0", ptr.To(intstr.FromInt32(0)), 6),
+		Entry("means no surge with maxUnavailable 1", ptr.To(intstr.FromInt32(1)), 4),
+	)
+})
+
+var _ = Describe("servers without player counts", func() {
+	ctx := context.Background()
+
+	// unknown is a Ready server probed over TCP: it reports no count.
+	unknown := func(i int, annos ...string) gamev1alpha1.GameServer {
+		gs := fleetServer(i, gamev1alpha1.GameServerStateReady, 0, annos...)
+		gs.Labels[gamev1alpha1.RevisionLabel] = "old"
+		gs.Status.PlayersUnknown = true
+		return gs
+	}
+	names := func(c client.Client) []string {
+		var left gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &left)).To(Succeed())
+		var out []string
+		for _, gs := range left.Items {
+			out = append(out, gs.Name)
+		}
+		return out
+	}
+
+	It("survive the drain pass", func() {
+		empty := fleetServer(1, gamev1alpha1.GameServerStateReady, 0, drainAnno)
+		empty.Labels[gamev1alpha1.RevisionLabel] = "old"
+		r, c := newScalingReconciler([]gamev1alpha1.GameServer{unknown(0, drainAnno), empty})
+		surge, unavailable := intstr.FromInt32(0), intstr.FromInt32(2)
+		gsd := bufferFleet(intstr.FromInt32(0), 0, 10)
+		gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: &unavailable}
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(c)).To(ContainElement("fleet-30000"))
+		Expect(names(c)).NotTo(ContainElement("fleet-30001"))
+	})
+
+	It("are not taken down at maxReplicas", func() {
+		r, c := newScalingReconciler([]gamev1alpha1.GameServer{unknown(0), unknown(1)})
+		surge, unavailable := intstr.FromInt32(1), intstr.FromInt32(1)
+		gsd := bufferFleet(intstr.FromInt32(0), 2, 2)
+		gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: &unavailable}
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(c)).To(ConsistOf("fleet-30000", "fleet-30001"))
+	})
+
+	It("are never removed by a scale-down", func() {
+		servers := []gamev1alpha1.GameServer{unknown(0), unknown(1), fleetServer(2, gamev1alpha1.GameServerStateReady, 0)}
+		r, _ := newScalingReconciler(servers)
+		Expect(isAvailable(&servers[0])).To(BeFalse())
+		left, _, err := r.scaleTo(ctx, bufferFleet(intstr.FromInt32(0), 0, 10), servers, 0, "test",
+			newPortPool(gamev1alpha1.PortRange{Start: 30000, End: 30099}, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(left).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("scaling during a rollout", func() {
+	ctx := context.Background()
+
+	// Draining servers count toward minReplicas, so no policy tops the surged
+	// servers up to minReplicas on its own and the rollout stays within maxSurge.
+	DescribeTable("keeps the fleet within maxSurge",
+		func(policy string) {
+			servers := make([]gamev1alpha1.GameServer, 10)
+			for i := range servers {
+				servers[i] = fleetServer(i, gamev1alpha1.GameServerStateReady, 3)
+				servers[i].Labels[gamev1alpha1.RevisionLabel] = "old"
+			}
+			r, c := newScalingReconciler(servers)
+			surge, unavailable := intstr.FromInt32(2), intstr.FromInt32(0)
+			gsd := bufferFleet(intstr.FromInt32(2), 10, 30)
+			gsd.Spec.ScaleUpThresholdPercent = 80
+			gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{MaxSurge: &surge, MaxUnavailable: &unavailable}
+			switch policy {
+			case gamev1alpha1.ScalingPolicyThreshold:
+				gsd.Spec.Scaling = nil
+			case gamev1alpha1.ScalingPolicyUtilization:
+				gsd.Spec.Scaling = &gamev1alpha1.Scaling{Policy: policy, TargetUtilizationPercent: 70}
+			case gamev1alpha1.ScalingPolicyWebhook:
+				// The webhook sizes the current servers: the two surged ones are plenty.
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					var rev autoscaler.Review
+					Expect(json.NewDecoder(req.Body).Decode(&rev)).To(Succeed())
+					_ = json.NewEncoder(w).Encode(autoscaler.Review{Response: &autoscaler.Response{UID: rev.Request.UID, Replicas: 2}})
+				}))
+				DeferCleanup(srv.Close)
+				gsd.Spec.Scaling = &gamev1alpha1.Scaling{Policy: policy,
+					Webhook: &admissionregistrationv1.WebhookClientConfig{URL: &srv.URL}}
+			}
+			Expect(c.Create(ctx, gsd)).To(Succeed())
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+			Expect(err).NotTo(HaveOccurred())
+			var left gamev1alpha1.GameServerList
+			Expect(c.List(ctx, &left)).To(Succeed())
+			Expect(left.Items).To(HaveLen(12)) // 10 draining + maxSurge 2
+		},
+		Entry("Threshold", gamev1alpha1.ScalingPolicyThreshold),
+		Entry("Buffer", gamev1alpha1.ScalingPolicyBuffer),
+		Entry("Utilization", gamev1alpha1.ScalingPolicyUtilization),
+		Entry("Webhook", gamev1alpha1.ScalingPolicyWebhook),
+	)
+})
+
+var _ = Describe("disruptive update strategies", func() {
+	ctx := context.Background()
+
+	// rollOnce reconciles a fleet of four busy outdated servers once under
+	// strategy and returns the servers left and the Events recorded.
+	rollOnce := func(strategy string) ([]gamev1alpha1.GameServer, []string) {
+		servers := make([]gamev1alpha1.GameServer, 4)
+		for i := range servers {
+			servers[i] = fleetServer(i, gamev1alpha1.GameServerStateReady, 5, allocatedAnno)
+			servers[i].Labels[gamev1alpha1.RevisionLabel] = "old"
+		}
+		r, c := newScalingReconciler(servers)
+		recorder := record.NewFakeRecorder(20)
+		r.Recorder = recorder
+		surge, unavailable := intstr.FromInt32(2), intstr.FromInt32(1)
+		gsd := bufferFleet(intstr.FromInt32(0), 0, 10)
+		gsd.Spec.Scaling = nil
+		gsd.Spec.UpdateStrategy = gamev1alpha1.UpdateStrategy{Type: strategy, MaxSurge: &surge, MaxUnavailable: &unavailable}
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+		Expect(err).NotTo(HaveOccurred())
+		var left gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &left)).To(Succeed())
+		close(recorder.Events)
+		var events []string
+		for e := range recorder.Events {
+			events = append(events, e)
+		}
+		return left.Items, events
+	}
+	outdatedIn := func(list []gamev1alpha1.GameServer) int {
+		n := 0
+		for _, gs := range list {
+			if gs.Labels[gamev1alpha1.RevisionLabel] == "old" {
+				n++
+			}
+		}
+		return n
+	}
+
+	withReason := func(events []string, reason string) []string {
+		var out []string
+		for _, e := range events {
+			if strings.Fields(e)[1] == reason {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+
+	It("Recreate deletes every outdated server at once, players or not", func() {
+		left, events := rollOnce(gamev1alpha1.UpdateStrategyRecreate)
+		Expect(outdatedIn(left)).To(BeZero())
+		evicted := withReason(events, "Evicted")
+		Expect(evicted).To(HaveLen(8)) // one on the fleet and one on the server each
+		Expect(evicted).To(ContainElement(ContainSubstring("Evicted Recreate: deleted outdated GameServer fleet-30000 with 5 players")))
+	})
+
+	It("RollingUpdate evicts within maxUnavailable and surges replacements", func() {
+		left, events := rollOnce(gamev1alpha1.UpdateStrategyRollingUpdate)
+		Expect(outdatedIn(left)).To(Equal(3))
+		Expect(left).To(HaveLen(6)) // target 4 + maxSurge 2
+		Expect(withReason(events, "Evicted")).To(HaveLen(2))
+		Expect(withReason(events, "Draining")).To(ContainElement(
+			MatchRegexp(`Draining 4 outdated GameServers with 20 players for revision \w+ \(4 outdated in all\)`)))
+		Expect(withReason(events, "ScalingUp")).To(ConsistOf(
+			MatchRegexp(`Scaled up from 3 to 6 GameServers: surged on revision \w+ to replace 3 outdated \(maxSurge 2\)`)))
+	})
+})
+
+var _ = Describe("pausing a fleet", func() {
+	ctx := context.Background()
+
+	// reconcileFleet reconciles servers once under gsd and returns what is left
+	// and the fleet as stored.
+	reconcileFleet := func(gsd *gamev1alpha1.GSDeployment, servers []gamev1alpha1.GameServer) ([]gamev1alpha1.GameServer, *gamev1alpha1.GSDeployment) {
+		r, _ := newScalingReconciler(nil)
+		b := fake.NewClientBuilder().WithScheme(r.Scheme).WithStatusSubresource(&gamev1alpha1.GSDeployment{})
+		for i := range servers {
+			b = b.WithObjects(&servers[i])
+		}
+		c := b.Build()
+		r.Client = c
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)})
+		Expect(err).NotTo(HaveOccurred())
+		var left gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &left)).To(Succeed())
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		return left.Items, &got
+	}
+
+	It("records a new revision but leaves outdated servers alone while paused", func() {
+		servers := make([]gamev1alpha1.GameServer, 3)
+		for i := range servers {
+			servers[i] = fleetServer(i, gamev1alpha1.GameServerStateReady, 3)
+			servers[i].Labels[gamev1alpha1.RevisionLabel] = "old"
+		}
+		gsd := bufferFleet(intstr.FromInt32(1), 0, 10)
+		gsd.Spec.Scaling = nil
+		gsd.Spec.Paused = true
+		gsd.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyRecreate
+
+		left, got := reconcileFleet(gsd, servers)
+		Expect(left).To(HaveLen(3))
+		for _, gs := range left {
+			Expect(gs.Labels).To(HaveKeyWithValue(gamev1alpha1.RevisionLabel, "old"))
+			Expect(isDraining(&gs)).To(BeFalse())
+		}
+		Expect(got.Status.UpdateRevision).NotTo(BeEmpty())
+		Expect(got.Status.CurrentRevision).To(BeEmpty()) // no server runs it yet
+		Expect(meta.IsStatusConditionTrue(got.Status.Conditions, condPaused)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(got.Status.Conditions, condScalingPaused)).To(BeFalse())
+	})
+
+	It("skips the scaling policy while autoscaling is paused", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 9)}
+		gsd := bufferFleet(intstr.FromInt32(1), 0, 10)
+		gsd.Spec.Scaling = nil // Threshold: a server at 90% would add one
+		gsd.Spec.AutoscalingPaused = true
+		gsd.Spec.Paused = true // keep the unlabelled server from rolling
+
+		left, got := reconcileFleet(gsd, servers)
+		Expect(left).To(HaveLen(1))
+		Expect(got.Status.DesiredReplicas).To(Equal(int32(1)))
+		Expect(meta.IsStatusConditionTrue(got.Status.Conditions, condScalingPaused)).To(BeTrue())
+	})
+})
+
+var _ = Describe("rollout conditions", func() {
+	var gsd *gamev1alpha1.GSDeployment
+	BeforeEach(func() {
+		gsd = bufferFleet(intstr.FromInt32(1), 2, 10)
+		gsd.Generation = 4
+	})
+	cond := func(st *gamev1alpha1.GSDeploymentStatus, t string) *metav1.Condition {
+		return meta.FindStatusCondition(st.Conditions, t)
+	}
+
+	It("reports a finished rollout", func() {
+		st := gamev1alpha1.GSDeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3,
+			CurrentRevision: "rev2", UpdateRevision: "rev2"}
+		setRolloutConditions(&st, gsd, 0, 0, false)
+		Expect(cond(&st, condAvailable).Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond(&st, condProgressing).Reason).To(Equal("NewRevisionAvailable"))
+		Expect(cond(&st, condProgressing).ObservedGeneration).To(Equal(int64(4)))
+		Expect(cond(&st, condReplicaFailure).Status).To(Equal(metav1.ConditionFalse))
+	})
+
+	It("reports a rollout in progress and too few available servers", func() {
+		st := gamev1alpha1.GSDeploymentStatus{Replicas: 4, UpdatedReplicas: 1, DrainingReplicas: 3, AvailableReplicas: 1,
+			CurrentRevision: "rev1", UpdateRevision: "rev2"}
+		setRolloutConditions(&st, gsd, 0, 0, false)
+		Expect(cond(&st, condAvailable).Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond(&st, condProgressing).Reason).To(Equal("RolloutInProgress"))
+		Expect(cond(&st, condProgressing).Message).To(Equal("1 of 4 GameServers updated to revision rev2, 3 draining"))
+
+		// maxUnavailable lowers the bar.
+		setRolloutConditions(&st, gsd, 1, 0, false)
+		Expect(cond(&st, condAvailable).Status).To(Equal(metav1.ConditionTrue))
+
+		gsd.Spec.Paused = true
+		setRolloutConditions(&st, gsd, 0, 0, false)
+		Expect(cond(&st, condProgressing).Status).To(Equal(metav1.ConditionUnknown))
+	})
+
+	It("counts Ready servers after the pass's deletes", func() {
+		ctx := context.Background()
+		r, _ := newScalingReconciler(nil)
+		c := fake.NewClientBuilder().WithScheme(r.Scheme).WithStatusSubresource(&gamev1alpha1.GSDeployment{}).Build()
+		r.Client = c
+		gsd.Spec.MinReplicas = 0
+		gsd.Spec.Scaling.BufferSize = ptr.To(intstr.FromInt32(4))
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)}
+
+		// The first pass creates four servers; report them Ready and empty.
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(4))
+		for _, gs := range list.Items {
+			gs.Status = gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateReady, MaxPlayers: 10}
+			Expect(c.Update(ctx, &gs)).To(Succeed())
+		}
+
+		// A smaller buffer removes three of them in the next pass.
+		Expect(c.Get(ctx, req.NamespacedName, gsd)).To(Succeed())
+		gsd.Spec.Scaling.BufferSize = ptr.To(intstr.FromInt32(1))
+		Expect(c.Update(ctx, gsd)).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, req.NamespacedName, &got)).To(Succeed())
+		Expect(got.Status.Replicas).To(Equal(int32(1)))
+		Expect(got.Status.ReadyReplicas).To(Equal(int32(1)))
+		Expect(testutil.ToFloat64(fleetReadyReplicas.WithLabelValues(gsd.Namespace, gsd.Name))).To(Equal(1.0))
+	})
+
+	It("reports replica failures", func() {
+		st := gamev1alpha1.GSDeploymentStatus{}
+		setRolloutConditions(&st, gsd, 0, 2, false)
+		Expect(cond(&st, condReplicaFailure).Reason).To(Equal("GameServersUnhealthy"))
+		setRolloutConditions(&st, gsd, 0, 0, true)
+		Expect(cond(&st, condReplicaFailure).Reason).To(Equal("PortRangeExhausted"))
+	})
+})
+
+var _ = Describe("portPool", func() {
+	It("hands out each port once with the Cluster policy", func() {
+		pool := newPortPool(gamev1alpha1.PortRange{Start: 30000, End: 30001}, 1)
+		pool.take(30000)
+		port, ok := pool.allocate()
+		Expect(ok).To(BeTrue())
+		Expect(port).To(Equal(int32(30001)))
+		_, ok = pool.allocate()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reuses a port once per node with the PerNode policy", func() {
+		pool := newPortPool(gamev1alpha1.PortRange{Start: 30000, End: 30000}, 3)
+		for range 3 {
+			port, ok := pool.allocate()
+			Expect(ok).To(BeTrue())
+			Expect(port).To(Equal(int32(30000)))
+		}
+		_, ok := pool.allocate()
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Game-specific metrics, served on the manager's metrics endpoint next to the
+// built-in controller-runtime ones. Status poll metrics live in internal/poller.
+const metricsNamespace = "gameserver_operator"
+
+var (
+	fleetLabels = []string{"namespace", "gsdeployment"}
+	gsLabels    = []string{"namespace", "gameserver"}
+
+	fleetReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_replicas",
+		Help: "GameServers owned by the GSDeployment.",
+	}, fleetLabels)
+	fleetReadyReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_ready_replicas",
+		Help: "Running GameServers owned by the GSDeployment.",
+	}, fleetLabels)
+	fleetDesiredReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_desired_replicas",
+		Help: "Non-draining GameServers the GSDeployment's scaling policy asked for.",
+	}, fleetLabels)
+	fleetDrainingReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_draining_replicas",
+		Help: "GameServers of the GSDeployment marked as draining.",
+	}, fleetLabels)
+	fleetPlayers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_players",
+		Help: "Players across all GameServers of the GSDeployment.",
+	}, fleetLabels)
+	fleetCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gsdeployment_capacity",
+		Help: "Sum of maxPlayers across all GameServers of the GSDeployment.",
+	}, fleetLabels)
+
+	gsPlayers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gameserver_players",
+		Help: "Players reported by the GameServer's last status poll or heartbeat.",
+	}, gsLabels)
+	gsMaxPlayers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace, Name: "gameserver_max_players",
+		Help: "maxPlayers reported by the GameServer's last status poll or heartbeat.",
+	}, gsLabels)
+
+	scaleActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace, Name: "scale_actions_total",
+		Help: "GameServers created (up) or deleted (down) by the GSDeployment controller.",
+	}, []string{"namespace", "gsdeployment", "direction"})
+	portRangeExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace, Name: "port_range_exhausted_total",
+		Help: "Times a GameServer could not be created because the port range was full.",
+	}, fleetLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		fleetReplicas, fleetReadyReplicas, fleetDesiredReplicas, fleetDrainingReplicas, fleetPlayers, fleetCapacity,
+		gsPlayers, gsMaxPlayers,
+		scaleActions, portRangeExhausted,
+	)
+}
+
+// forgetFleetMetrics drops the series of a deleted GSDeployment.
+func forgetFleetMetrics(namespace, name string) {
+	for _, g := range []*prometheus.GaugeVec{fleetReplicas, fleetReadyReplicas, fleetDesiredReplicas, fleetDrainingReplicas, fleetPlayers, fleetCapacity} {
+		g.DeleteLabelValues(namespace, name)
+	}
+	portRangeExhausted.DeleteLabelValues(namespace, name)
+	scaleActions.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "gsdeployment": name})
+}
+
+// forgetGameServerMetrics drops the series of a deleted GameServer.
+func forgetGameServerMetrics(namespace, name string) {
+	gsPlayers.DeleteLabelValues(namespace, name)
+	gsMaxPlayers.DeleteLabelValues(namespace, name)
+}
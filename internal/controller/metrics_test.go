@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("fleet metrics", func() {
+	ctx := context.Background()
+
+	It("reports the fleet after a reconcile and forgets it once deleted", func() {
+		r, _ := newScalingReconciler(nil)
+		c := fake.NewClientBuilder().WithScheme(r.Scheme).WithStatusSubresource(&gamev1alpha1.GSDeployment{}).Build()
+		r.Client = c
+		gsd := bufferFleet(intstr.FromInt32(0), 2, 10)
+		gsd.Namespace = "metrics"
+		gsd.Spec.Scaling = nil
+		gsd.Spec.ScaleUpThresholdPercent = 80
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(gsd)}
+
+		// The first pass creates minReplicas servers; report them Ready and busy.
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list, client.InNamespace("metrics"))).To(Succeed())
+		Expect(list.Items).To(HaveLen(2))
+		for i, gs := range list.Items {
+			gs.Status = gamev1alpha1.GameServerStatus{State: gamev1alpha1.GameServerStateReady, Players: int32(3 + i), MaxPlayers: 10}
+			Expect(c.Update(ctx, &gs)).To(Succeed())
+		}
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		for g, want := range map[*prometheus.GaugeVec]float64{
+			fleetReplicas:         2,
+			fleetReadyReplicas:    2,
+			fleetDesiredReplicas:  2,
+			fleetDrainingReplicas: 0,
+			fleetPlayers:          7,
+			fleetCapacity:         20,
+		} {
+			Expect(testutil.ToFloat64(g.WithLabelValues("metrics", "fleet"))).To(Equal(want))
+		}
+		Expect(testutil.ToFloat64(scaleActions.WithLabelValues("metrics", "fleet", "up"))).To(Equal(2.0))
+
+		Expect(c.Delete(ctx, gsd)).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		for _, g := range []*prometheus.GaugeVec{fleetReplicas, fleetReadyReplicas, fleetDesiredReplicas,
+			fleetDrainingReplicas, fleetPlayers, fleetCapacity} {
+			Expect(g.DeletePartialMatch(prometheus.Labels{"namespace": "metrics"})).To(BeZero())
+		}
+		Expect(scaleActions.DeletePartialMatch(prometheus.Labels{"namespace": "metrics"})).To(BeZero())
+	})
+})
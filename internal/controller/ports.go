@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"sort"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// portPool hands out ports from a GSDeployment's portRange.
+//
+// Game servers run with hostNetwork, so a port only has to be unique per node.
+// With PortPolicyPerNode a port may be handed out once per eligible node; the
+// scheduler's host-port check then keeps two servers with the same port off
+// the same node. With PortPolicyCluster (default) every port is used once.
+type portPool struct {
+	start, end int32
+	perPort    int32 // how many servers may share one port
+	used       map[int32]int32
+	exhausted  bool // an allocation found no free port
+}
+
+func newPortPool(pr gamev1alpha1.PortRange, perPort int32) *portPool {
+	if perPort < 1 {
+		perPort = 1
+	}
+	return &portPool{start: pr.Start, end: pr.End, perPort: perPort, used: map[int32]int32{}}
+}
+
+func (p *portPool) take(port int32) {
+	p.used[port]++
+}
+
+// allocate returns the lowest port that still has room and takes it.
+func (p *portPool) allocate() (int32, bool) {
+	port, ok := allocatePort(p.used, p.start, p.end, p.perPort)
+	if ok {
+		p.take(port)
+	} else {
+		p.exhausted = true
+	}
+	return port, ok
+}
+
+// servers is how many servers hold a port from the pool.
+func (p *portPool) servers() int32 {
+	var n int32
+	for _, c := range p.used {
+		n += c
+	}
+	return n
+}
+
+func allocatePort(used map[int32]int32, start, end, perPort int32) (int32, bool) {
+	for p := start; p <= end; p++ {
+		if used[p] < perPort {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// eligibleNodes counts schedulable nodes matching the fleet's node selector.
+// Taints and affinity are not evaluated; a server that cannot fit stays Pending.
+func eligibleNodes(ctx context.Context, c client.Reader, gsd *gamev1alpha1.GSDeployment) (int32, error) {
+	sel := map[string]string{}
+	if gsd.Spec.Template != nil {
+		for k, v := range gsd.Spec.Template.Spec.NodeSelector {
+			sel[k] = v
+		}
+	}
+	for k, v := range gsd.Spec.NodeSelector {
+		sel[k] = v
+	}
+	var nodes corev1.NodeList
+	if err := c.List(ctx, &nodes, client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(sel)}); err != nil {
+		return 0, err
+	}
+	n := int32(0)
+	for _, node := range nodes.Items {
+		if !node.Spec.Unschedulable {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// nodePortUsage groups the children's ports by the node they run on.
+// Servers not yet scheduled are reported under an empty node name.
+func nodePortUsage(children []gamev1alpha1.GameServer) []gamev1alpha1.NodePortUsage {
+	byNode := map[string][]int32{}
+	for _, gs := range children {
+		byNode[gs.Status.NodeName] = append(byNode[gs.Status.NodeName], gs.Spec.Port)
+	}
+	out := make([]gamev1alpha1.NodePortUsage, 0, len(byNode))
+	for node, ports := range byNode {
+		sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+		out = append(out, gamev1alpha1.NodePortUsage{NodeName: node, Ports: ports})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NodeName < out[j].NodeName })
+	return out
+}
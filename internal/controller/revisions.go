@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=game.example.com,resources=gameserversets,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	revisionNumberAnno = "game.example.com/revision-number" // on a GameServerSet: its place in the history
+	changeCauseAnno    = "kubernetes.io/change-cause"       // copied from the GSDeployment to the set
+
+	defaultRevisionHistoryLimit = 10
+)
+
+// specRevision hashes a server spec, port aside, into a short label-safe
+// revision. Any field that differs gives a different revision.
+func specRevision(spec gamev1alpha1.GameServerSpec) string {
+	spec.Port = 0
+	b, err := json.Marshal(spec)
+	if err != nil {
+		// Plain data; cannot happen.
+		panic(err)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(b)
+	return rand.SafeEncodeString(fmt.Sprint(h.Sum32()))
+}
+
+// setLabels are the labels of a fleet's GameServerSet for a revision, and of
+// that set's GameServers.
+func setLabels(owner, revision string) map[string]string {
+	l := childLabels(owner)
+	l[gamev1alpha1.RevisionLabel] = revision
+	return l
+}
+
+// ensureSet returns the fleet's GameServerSet for tmpl, creating it first if
+// this revision is new. The set gets the next revision number, and so does an
+// older set that becomes current again.
+func (r *GSDeploymentReconciler) ensureSet(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	tmpl gamev1alpha1.GameServerSpec) (*gamev1alpha1.GameServerSet, error) {
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return nil, err
+	}
+	var latest int64
+	for _, s := range sets {
+		latest = max(latest, revisionNumber(&s))
+	}
+	rev := specRevision(tmpl)
+	key := types.NamespacedName{Namespace: gsd.Namespace, Name: fmt.Sprintf("%s-%s", gsd.Name, rev)}
+	for i := range sets {
+		set := &sets[i]
+		if set.Name != key.Name {
+			continue
+		}
+		if n := revisionNumber(set); n == 0 || n < latest {
+			setRevisionAnnotations(set, gsd, latest+1)
+			if err := r.Update(ctx, set); err != nil {
+				return nil, err
+			}
+		}
+		return set, nil
+	}
+
+	set := gamev1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: setLabels(gsd.Name, rev)},
+		Spec:       gamev1alpha1.GameServerSetSpec{Revision: rev, Template: tmpl},
+	}
+	setRevisionAnnotations(&set, gsd, latest+1)
+	if err := ctrl.SetControllerReference(gsd, &set, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, &set); err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return &set, r.Get(ctx, key, &set)
+		}
+		return nil, err
+	}
+	ctrl.Log.WithName("gsdeployment").Info("created GameServerSet", "gameserverset", set.Name, "revision", latest+1)
+	return &set, nil
+}
+
+// setRevisionAnnotations numbers set and records why the fleet moved to it.
+func setRevisionAnnotations(set *gamev1alpha1.GameServerSet, gsd *gamev1alpha1.GSDeployment, n int64) {
+	if set.Annotations == nil {
+		set.Annotations = map[string]string{}
+	}
+	set.Annotations[revisionNumberAnno] = strconv.FormatInt(n, 10)
+	if cause := gsd.Annotations[changeCauseAnno]; cause != "" {
+		set.Annotations[changeCauseAnno] = cause
+	}
+}
+
+// revisionNumber is the set's place in the fleet's history, 0 if unknown.
+func revisionNumber(set *gamev1alpha1.GameServerSet) int64 {
+	n, _ := strconv.ParseInt(set.Annotations[revisionNumberAnno], 10, 64)
+	return n
+}
+
+// fleetSets lists the fleet's GameServerSets, newest revision first.
+func (r *GSDeploymentReconciler) fleetSets(ctx context.Context, gsd *gamev1alpha1.GSDeployment) ([]gamev1alpha1.GameServerSet, error) {
+	var sets gamev1alpha1.GameServerSetList
+	if err := r.List(ctx, &sets, client.InNamespace(gsd.Namespace), client.MatchingLabels(childLabels(gsd.Name))); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sets.Items, func(i, j int) bool {
+		return revisionNumber(&sets.Items[i]) > revisionNumber(&sets.Items[j])
+	})
+	return sets.Items, nil
+}
+
+// servingSet is the newest set with servers in service, or current if none:
+// the set a paused rollout grows, so a new template is not rolled out by
+// scaling either.
+func (r *GSDeploymentReconciler) servingSet(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	current *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) (*gamev1alpha1.GameServerSet, error) {
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return nil, err
+	}
+	serving := map[string]bool{}
+	for _, gs := range children {
+		if !isDraining(&gs) {
+			serving[gs.Labels[gamev1alpha1.RevisionLabel]] = true
+		}
+	}
+	for i := range sets {
+		if serving[sets[i].Spec.Revision] {
+			return &sets[i], nil
+		}
+	}
+	return current, nil
+}
+
+// adopt moves a GameServer created before GameServerSets existed into set:
+// it gets the revision label and the set becomes its controller.
+func (r *GSDeploymentReconciler) adopt(ctx context.Context, set *gamev1alpha1.GameServerSet, gs *gamev1alpha1.GameServer) error {
+	refs := gs.OwnerReferences[:0]
+	for _, ref := range gs.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			refs = append(refs, ref)
+		}
+	}
+	gs.OwnerReferences = refs
+	if err := ctrl.SetControllerReference(set, gs, r.Scheme); err != nil {
+		return err
+	}
+	if gs.Labels == nil {
+		gs.Labels = map[string]string{}
+	}
+	gs.Labels[gamev1alpha1.RevisionLabel] = set.Spec.Revision
+	return r.Update(ctx, gs)
+}
+
+// pruneSets deletes the fleet's GameServerSets whose servers are all gone,
+// beyond the newest revisionHistoryLimit of them; the current set always
+// stays. Deletion orphans: a server our cache has not seen yet outlives its
+// set and is rolled like any other outdated server. It returns the sets kept,
+// newest first.
+func (r *GSDeploymentReconciler) pruneSets(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	current *gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) ([]gamev1alpha1.GameServerSet, error) {
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return nil, err
+	}
+	limit := int32(defaultRevisionHistoryLimit)
+	if gsd.Spec.RevisionHistoryLimit != nil {
+		limit = *gsd.Spec.RevisionHistoryLimit
+	}
+	inUse := map[string]bool{}
+	for _, gs := range children {
+		inUse[gs.Labels[gamev1alpha1.RevisionLabel]] = true
+	}
+	kept := sets[:0]
+	var idle int32
+	for _, set := range sets {
+		if set.Name == current.Name || inUse[set.Spec.Revision] {
+			kept = append(kept, set)
+			continue
+		}
+		if idle < limit {
+			idle++
+			kept = append(kept, set)
+			continue
+		}
+		if err := r.Delete(ctx, &set, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// revisionHistory reports sets, newest first, with their running servers.
+func revisionHistory(sets []gamev1alpha1.GameServerSet, children []gamev1alpha1.GameServer) []gamev1alpha1.RevisionRecord {
+	running := map[string]int32{}
+	for _, gs := range children {
+		running[gs.Labels[gamev1alpha1.RevisionLabel]]++
+	}
+	out := make([]gamev1alpha1.RevisionRecord, 0, len(sets))
+	for _, set := range sets {
+		out = append(out, gamev1alpha1.RevisionRecord{
+			Revision:          revisionNumber(&set),
+			GameServerSet:     set.Name,
+			ChangeCause:       set.Annotations[changeCauseAnno],
+			Replicas:          running[set.Spec.Revision],
+			CreationTimestamp: set.CreationTimestamp,
+		})
+	}
+	return out
+}
+
+// rollback handles spec.rollbackTo: it copies the chosen revision's template
+// back into the spec and clears rollbackTo. The spec change then rolls the
+// fleet through the usual surge and drain. Outcomes are reported as Events.
+func (r *GSDeploymentReconciler) rollback(ctx context.Context, gsd *gamev1alpha1.GSDeployment) error {
+	sets, err := r.fleetSets(ctx, gsd)
+	if err != nil {
+		return err
+	}
+	want := gsd.Spec.RollbackTo.Revision
+	gsd.Spec.RollbackTo = nil
+	current := specTemplateRevision(gsd)
+	target := rollbackTarget(sets, current, want)
+	switch {
+	case target == nil && want == 0:
+		r.eventf(gsd, corev1.EventTypeWarning, "RollbackRevisionNotFound", "Unable to find the previous revision to roll back to")
+	case target == nil:
+		r.eventf(gsd, corev1.EventTypeWarning, "RollbackRevisionNotFound", "Unable to find revision %d to roll back to", want)
+	case target.Spec.Revision == current:
+		r.eventf(gsd, corev1.EventTypeNormal, "RollbackTemplateUnchanged", "Revision %d is already current", revisionNumber(target))
+	default:
+		restoreTemplate(gsd, target.Spec.Template)
+		r.eventf(gsd, corev1.EventTypeNormal, "RollbackDone", "Rolled back to revision %d (%s)", revisionNumber(target), target.Name)
+	}
+	return r.Update(ctx, gsd)
+}
+
+// rollbackTarget picks revision want from sets (newest first), or for 0 the
+// newest one other than current, the spec's own revision. A template edit
+// made together with rollbackTo has no set yet, so the set last rolled out
+// is the one restored then.
+func rollbackTarget(sets []gamev1alpha1.GameServerSet, current string, want int64) *gamev1alpha1.GameServerSet {
+	for i := range sets {
+		if want == 0 && sets[i].Spec.Revision != current || want != 0 && revisionNumber(&sets[i]) == want {
+			return &sets[i]
+		}
+	}
+	return nil
+}
+
+// specTemplateRevision is the revision of the template the spec asks for,
+// with the defaults Reconcile applies.
+func specTemplateRevision(gsd *gamev1alpha1.GSDeployment) string {
+	d := gsd.DeepCopy()
+	defaultTemplate(&d.Spec)
+	return specRevision(childSpec(d, maxPlayersParam(d)))
+}
+
+// restoreTemplate is the inverse of childSpec: it sets the fleet's spec so
+// that childSpec yields t again. MAX_PLAYERS goes back into
+// parameters.maxPlayers when the fleet sets it there.
+func restoreTemplate(gsd *gamev1alpha1.GSDeployment, t gamev1alpha1.GameServerSpec) {
+	gsd.Spec.Image = t.Image
+	gsd.Spec.PollPath = t.PollPath
+	gsd.Spec.Env = t.Env
+	gsd.Spec.Resources = t.Resources
+	gsd.Spec.NodeSelector = t.NodeSelector
+	gsd.Spec.Template = t.Template
+	gsd.Spec.Container = t.Container
+	gsd.Spec.StatusProbe = t.StatusProbe
+	gsd.Spec.StatusMode = t.StatusMode
+	gsd.Spec.HeartbeatTimeoutSeconds = t.HeartbeatTimeoutSeconds
+	if p := gsd.Spec.Parameters; p != nil && p.MaxPlayers != nil {
+		p.MaxPlayers = nil
+		for _, e := range t.Env {
+			if n, err := strconv.ParseInt(e.Value, 10, 32); e.Name == "MAX_PLAYERS" && err == nil {
+				p.MaxPlayers = ptr.To(int32(n))
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("specRevision", func() {
+	base := gamev1alpha1.GameServerSpec{Image: "game:v1", Port: 30000, PollPath: "/status"}
+
+	It("ignores the port", func() {
+		other := base
+		other.Port = 30001
+		Expect(specRevision(other)).To(Equal(specRevision(base)))
+	})
+
+	DescribeTable("changes with any other field",
+		func(change func(*gamev1alpha1.GameServerSpec)) {
+			other := *base.DeepCopy()
+			change(&other)
+			Expect(specRevision(other)).NotTo(Equal(specRevision(base)))
+		},
+		Entry("resources", func(s *gamev1alpha1.GameServerSpec) {
+			s.Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}
+		}),
+		Entry("nodeSelector", func(s *gamev1alpha1.GameServerSpec) { s.NodeSelector = map[string]string{"pool": "games"} }),
+		Entry("any env var", func(s *gamev1alpha1.GameServerSpec) { s.Env = []corev1.EnvVar{{Name: "MAP", Value: "dust"}} }),
+		Entry("pollPath", func(s *gamev1alpha1.GameServerSpec) { s.PollPath = "/healthz" }),
+	)
+})
+
+var _ = Describe("GameServerSets", func() {
+	ctx := context.Background()
+
+	var gsd *gamev1alpha1.GSDeployment
+	BeforeEach(func() {
+		gsd = bufferFleet(intstr.FromInt32(1), 0, 10)
+		gsd.Spec.Image = "game:v1"
+	})
+
+	It("creates one set per revision and reuses it", func() {
+		r, c := newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+
+		set, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(set.Name).To(Equal("fleet-" + set.Spec.Revision))
+		Expect(set.Labels).To(HaveKeyWithValue(gamev1alpha1.RevisionLabel, set.Spec.Revision))
+		Expect(metav1.IsControlledBy(set, gsd)).To(BeTrue())
+
+		again, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.UID).To(Equal(set.UID))
+
+		gsd.Spec.Image = "game:v2"
+		next, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next.Name).NotTo(Equal(set.Name))
+	})
+
+	It("creates servers from the set's template, owned by the set", func() {
+		r, c := newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		set, err := r.ensureSet(ctx, gsd, childSpec(gsd, "16"))
+		Expect(err).NotTo(HaveOccurred())
+
+		gs, err := r.createChild(ctx, gsd, set, newPortPool(gsd.Spec.PortRange, 1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gs.Spec.Port).To(Equal(int32(30000)))
+		Expect(gs.Spec.Env).To(ContainElement(corev1.EnvVar{Name: "MAX_PLAYERS", Value: "16"}))
+		Expect(gs.Labels).To(HaveKeyWithValue(gamev1alpha1.RevisionLabel, set.Spec.Revision))
+		Expect(metav1.IsControlledBy(gs, set)).To(BeTrue())
+	})
+
+	It("adopts a current server created before sets existed", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 3)}
+		servers[0].Spec = childSpec(gsd, "")
+		servers[0].Spec.Port = 30000
+		r, c := newScalingReconciler(servers)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		legacy := servers[0]
+		set, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specRevision(legacy.Spec)).To(Equal(set.Spec.Revision))
+
+		Expect(r.adopt(ctx, set, &legacy)).To(Succeed())
+		var got gamev1alpha1.GameServer
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&legacy), &got)).To(Succeed())
+		Expect(got.Labels).To(HaveKeyWithValue(gamev1alpha1.RevisionLabel, set.Spec.Revision))
+		Expect(metav1.IsControlledBy(&got, set)).To(BeTrue())
+	})
+
+	It("deletes old sets only once their servers are gone", func() {
+		r, c := newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		old, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Spec.Image = "game:v2"
+		current, err := r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+		Expect(err).NotTo(HaveOccurred())
+
+		busy := fleetServer(0, gamev1alpha1.GameServerStateReady, 3)
+		busy.Labels[gamev1alpha1.RevisionLabel] = old.Spec.Revision
+		gsd.Spec.RevisionHistoryLimit = ptr.To(int32(0))
+		kept, err := r.pruneSets(ctx, gsd, current, []gamev1alpha1.GameServer{busy})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kept).To(HaveLen(2))
+
+		kept, err = r.pruneSets(ctx, gsd, current, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kept).To(ConsistOf(HaveField("Name", current.Name)))
+		var sets gamev1alpha1.GameServerSetList
+		Expect(c.List(ctx, &sets)).To(Succeed())
+		Expect(sets.Items).To(ConsistOf(HaveField("Name", current.Name)))
+	})
+
+	It("keeps revisionHistoryLimit idle revisions, newest first", func() {
+		r, c := newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+		var current *gamev1alpha1.GameServerSet
+		for _, image := range []string{"game:v1", "game:v2", "game:v3", "game:v4"} {
+			gsd.Spec.Image = image
+			var err error
+			current, err = r.ensureSet(ctx, gsd, childSpec(gsd, ""))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(revisionNumber(current)).To(Equal(int64(4)))
+
+		gsd.Spec.RevisionHistoryLimit = ptr.To(int32(2))
+		kept, err := r.pruneSets(ctx, gsd, current, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revisionHistory(kept, nil)).To(HaveExactElements(
+			HaveField("Revision", int64(4)), HaveField("Revision", int64(3)), HaveField("Revision", int64(2))))
+	})
+})
+
+var _ = Describe("rollback", func() {
+	ctx := context.Background()
+
+	var (
+		gsd *gamev1alpha1.GSDeployment
+		r   *GSDeploymentReconciler
+		c   client.Client
+		v1  *gamev1alpha1.GameServerSet
+	)
+	BeforeEach(func() {
+		gsd = bufferFleet(intstr.FromInt32(1), 0, 10)
+		gsd.Spec.Image = "game:v1"
+		gsd.Spec.PollPath = "/status" // as Reconcile defaults it before building sets
+		gsd.Spec.Parameters = &gamev1alpha1.Parameters{MaxPlayers: ptr.To(int32(16))}
+		gsd.Annotations = map[string]string{changeCauseAnno: "first build"}
+		r, c = newScalingReconciler(nil)
+		Expect(c.Create(ctx, gsd)).To(Succeed())
+
+		var err error
+		v1, err = r.ensureSet(ctx, gsd, childSpec(gsd, "16"))
+		Expect(err).NotTo(HaveOccurred())
+		gsd.Spec.Image = "game:v2"
+		gsd.Spec.Parameters.MaxPlayers = ptr.To(int32(24))
+		gsd.Spec.Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}
+		gsd.Annotations[changeCauseAnno] = "bad build"
+		_, err = r.ensureSet(ctx, gsd, childSpec(gsd, "24"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("restores the previous template and clears rollbackTo", func() {
+		gsd.Spec.RollbackTo = &gamev1alpha1.RollbackConfig{}
+		Expect(r.rollback(ctx, gsd)).To(Succeed())
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Spec.RollbackTo).To(BeNil())
+		Expect(got.Spec.Image).To(Equal("game:v1"))
+		Expect(*got.Spec.Parameters.MaxPlayers).To(Equal(int32(16)))
+		Expect(specRevision(childSpec(&got, "16"))).To(Equal(v1.Spec.Revision))
+	})
+
+	It("gives the revision rolled back to the next number", func() {
+		gsd.Spec.RollbackTo = &gamev1alpha1.RollbackConfig{Revision: 1}
+		Expect(r.rollback(ctx, gsd)).To(Succeed())
+		gsd.Annotations[changeCauseAnno] = "rollback to v1"
+
+		set, err := r.ensureSet(ctx, gsd, childSpec(gsd, "16"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(set.Name).To(Equal(v1.Name))
+		Expect(revisionNumber(set)).To(Equal(int64(3)))
+		Expect(set.Annotations).To(HaveKeyWithValue(changeCauseAnno, "rollback to v1"))
+	})
+
+	It("restores the named revision when the template was edited in the same update", func() {
+		gsd.Spec.Image = "game:v3"
+		gsd.Spec.RollbackTo = &gamev1alpha1.RollbackConfig{Revision: 2}
+		Expect(r.rollback(ctx, gsd)).To(Succeed())
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Spec.RollbackTo).To(BeNil())
+		Expect(got.Spec.Image).To(Equal("game:v2"))
+		Expect(*got.Spec.Parameters.MaxPlayers).To(Equal(int32(24)))
+	})
+
+	It("undoes a template edit made together with a rollback to the previous revision", func() {
+		gsd.Spec.Image = "game:v3"
+		gsd.Spec.RollbackTo = &gamev1alpha1.RollbackConfig{}
+		Expect(r.rollback(ctx, gsd)).To(Succeed())
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Spec.Image).To(Equal("game:v2"))
+		Expect(*got.Spec.Parameters.MaxPlayers).To(Equal(int32(24)))
+	})
+
+	It("leaves the spec alone for an unknown revision", func() {
+		gsd.Spec.RollbackTo = &gamev1alpha1.RollbackConfig{Revision: 7}
+		Expect(r.rollback(ctx, gsd)).To(Succeed())
+
+		var got gamev1alpha1.GSDeployment
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(gsd), &got)).To(Succeed())
+		Expect(got.Spec.RollbackTo).To(BeNil())
+		Expect(got.Spec.Image).To(Equal("game:v2"))
+	})
+})
@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/autoscaler"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	defaultBufferSize        = 2
+	defaultTargetUtilization = 70
+	// Webhook policy: how often to ask again while the fleet itself is quiet.
+	webhookResync = 30 * time.Second
+)
+
+// scalingPolicy returns the fleet's autoscaling policy, defaulting to Threshold.
+func scalingPolicy(gsd *gamev1alpha1.GSDeployment) string {
+	if gsd.Spec.Scaling == nil || gsd.Spec.Scaling.Policy == "" {
+		return gamev1alpha1.ScalingPolicyThreshold
+	}
+	return gsd.Spec.Scaling.Policy
+}
+
+// applySchedule overlays an open schedule window on the fleet's spec (in
+// memory only). minReplicas never exceeds maxReplicas.
+func applySchedule(gsd *gamev1alpha1.GSDeployment, s *gamev1alpha1.ScalingSchedule) {
+	if s == nil {
+		return
+	}
+	if s.MinReplicas != nil {
+		gsd.Spec.MinReplicas = *s.MinReplicas
+	}
+	if s.MaxReplicas != nil {
+		gsd.Spec.MaxReplicas = *s.MaxReplicas
+	}
+	gsd.Spec.MinReplicas = minInt32(gsd.Spec.MinReplicas, gsd.Spec.MaxReplicas)
+	if s.BufferSize != nil {
+		if gsd.Spec.Scaling == nil {
+			gsd.Spec.Scaling = &gamev1alpha1.Scaling{}
+		}
+		gsd.Spec.Scaling.BufferSize = s.BufferSize
+	}
+}
+
+// isAvailable reports whether a server counts toward the Buffer: current,
+// empty, unreserved, and Ready or still starting. Starting servers count so a burst of
+// creates is not repeated while their Pods come up.
+func isAvailable(gs *gamev1alpha1.GameServer) bool {
+	if isDraining(gs) || isAllocated(gs) || isReserved(gs) || !isEmpty(gs) || !gs.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return gs.Status.State == gamev1alpha1.GameServerStateReady || gs.Status.State.IsStarting()
+}
+
+// minActive is the fewest current (non-draining) servers a policy may size
+// the fleet to. minReplicas counts draining servers too, so a rollout is not
+// topped up past its maxSurge while the old servers drain.
+func minActive(gsd *gamev1alpha1.GSDeployment, children []gamev1alpha1.GameServer) int32 {
+	var draining int32
+	for _, gs := range children {
+		if isDraining(&gs) {
+			draining++
+		}
+	}
+	return maxInt32(gsd.Spec.MinReplicas-draining, 0)
+}
+
+// bufferTarget is how many non-draining servers the fleet needs so that size
+// of them are available while busy are in use. A percentage is of the whole
+// fleet: with "20%" and 8 busy servers the fleet needs 10.
+func bufferTarget(size *intstr.IntOrString, busy int32) int32 {
+	if size == nil {
+		return busy + defaultBufferSize
+	}
+	if size.Type == intstr.Int {
+		return busy + maxInt32(size.IntVal, 0)
+	}
+	pct, err := intstr.GetScaledValueFromIntOrPercent(size, 100, false)
+	if err != nil || pct < 0 {
+		pct = 0
+	}
+	if pct > 99 {
+		pct = 99
+	}
+	free := int32(100 - pct)
+	return (busy*100 + free - 1) / free
+}
+
+// scaleThreshold is the built-in rule: one more server when any server is at
+// or above scaleUpThresholdPercent, one fewer for every current server idle
+// for scaleDownZeroSeconds. A hot server next to a long-idle one is a wash:
+// the idle one is the spare capacity. Idle servers go oldest first; reserved
+// ones are not idle.
+func (r *GSDeploymentReconciler) scaleThreshold(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	children []gamev1alpha1.GameServer, used *portPool, set *gamev1alpha1.GameServerSet) ([]gamev1alpha1.GameServer, int32, error) {
+	now := time.Now()
+	var hot *gamev1alpha1.GameServer // fullest server at or above the threshold
+	var active int32
+	var idle []gamev1alpha1.GameServer
+	for i, gs := range children {
+		if mp := gs.Status.MaxPlayers; mp > 0 && gs.Status.Players*100/mp >= gsd.Spec.ScaleUpThresholdPercent &&
+			(hot == nil || gs.Status.Players*100/mp > hot.Status.Players*100/hot.Status.MaxPlayers) {
+			hot = &children[i]
+		}
+		if isDraining(&gs) {
+			continue
+		}
+		active++
+		if !isAllocated(&gs) && !isReserved(&gs) && isEmpty(&gs) && gs.Status.ZeroSince != nil &&
+			now.Sub(gs.Status.ZeroSince.Time) >= time.Duration(gsd.Spec.ScaleDownZeroSeconds)*time.Second {
+			idle = append(idle, gs)
+		}
+	}
+	recommended := active - int32(len(idle))
+	var why []string
+	if hot != nil {
+		recommended++
+		why = append(why, fmt.Sprintf("%s has %d/%d players (threshold %d%%)",
+			hot.Name, hot.Status.Players, hot.Status.MaxPlayers, gsd.Spec.ScaleUpThresholdPercent))
+	}
+	if len(idle) > 0 {
+		why = append(why, fmt.Sprintf("%d servers empty for %ds or more", len(idle), gsd.Spec.ScaleDownZeroSeconds))
+	}
+	target := maxInt32(minInt32(recommended, gsd.Spec.MaxReplicas), minActive(gsd, children))
+	target = r.behave(gsd, active, target)
+	from := active
+	defer func() {
+		r.scaleEvent(gsd, from, active, fmt.Sprintf("threshold policy, target %d: %s", target, strings.Join(why, "; ")))
+	}()
+
+	total := int32(len(children))
+	for ; active < target && total < gsd.Spec.MaxReplicas; active++ {
+		newGS, err := r.createChild(ctx, gsd, set, used)
+		if err != nil {
+			return children, target, err
+		}
+		if newGS == nil {
+			break
+		}
+		r.recordScale(gsd, 1)
+		children = append(children, *newGS)
+		total++
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].CreationTimestamp.Before(&idle[j].CreationTimestamp)
+	})
+	for _, gs := range idle {
+		if active <= target {
+			break
+		}
+		if err := r.Delete(ctx, &gs); err != nil && !kerrors.IsNotFound(err) {
+			return children, target, err
+		}
+		scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+		r.recordScale(gsd, -1)
+		children = removeGS(children, gs.Name)
+		active--
+	}
+	return children, target, nil
+}
+
+// scaleBuffer sizes the fleet so the available buffer is back at its target.
+func (r *GSDeploymentReconciler) scaleBuffer(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	children []gamev1alpha1.GameServer, used *portPool, set *gamev1alpha1.GameServerSet) ([]gamev1alpha1.GameServer, int32, error) {
+	var active, available int32
+	for _, gs := range children {
+		if isDraining(&gs) {
+			continue
+		}
+		active++
+		if isAvailable(&gs) {
+			available++
+		}
+	}
+	target := bufferTarget(gsd.Spec.Scaling.BufferSize, active-available)
+	ctrllog.FromContext(ctx).V(1).Info("buffer", "busy", active-available, "available", available, "target", target)
+	why := fmt.Sprintf("buffer policy, %d busy and %d available servers", active-available, available)
+	return r.scaleTo(ctx, gsd, children, target, why, used, set)
+}
+
+// utilizationTarget is how many current (non-draining) servers put the
+// fleet's players at targetUtilizationPercent of its capacity. A server's
+// capacity is the average maxPlayers reported by the fleet, or
+// parameters.maxPlayers until one reports; ok is false if neither is known.
+func utilizationTarget(gsd *gamev1alpha1.GSDeployment, children []gamev1alpha1.GameServer) (target int32, ok bool) {
+	var active, players, capacity, reporting int32
+	for _, gs := range children {
+		if isDraining(&gs) {
+			continue
+		}
+		active++
+		players += gs.Status.Players
+		if gs.Status.MaxPlayers > 0 {
+			capacity += gs.Status.MaxPlayers
+			reporting++
+		}
+	}
+	var perServer float64
+	switch {
+	case reporting > 0:
+		perServer = float64(capacity) / float64(reporting)
+	case gsd.Spec.Parameters != nil && gsd.Spec.Parameters.MaxPlayers != nil && *gsd.Spec.Parameters.MaxPlayers > 0:
+		perServer = float64(*gsd.Spec.Parameters.MaxPlayers)
+	default:
+		return active, false
+	}
+	pct := gsd.Spec.Scaling.TargetUtilizationPercent
+	if pct <= 0 || pct > 100 {
+		pct = defaultTargetUtilization
+	}
+	return int32(math.Ceil(float64(players) * 100 / (float64(pct) * perServer))), true
+}
+
+// scaleUtilization sizes the fleet toward its target utilization. Until some
+// capacity is known the fleet is left as it is.
+func (r *GSDeploymentReconciler) scaleUtilization(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	children []gamev1alpha1.GameServer, used *portPool, set *gamev1alpha1.GameServerSet) ([]gamev1alpha1.GameServer, int32, error) {
+	target, ok := utilizationTarget(gsd, children)
+	ctrllog.FromContext(ctx).V(1).Info("utilization", "target", target, "capacityKnown", ok)
+	var players int32
+	for _, gs := range children {
+		if !isDraining(&gs) {
+			players += gs.Status.Players
+		}
+	}
+	why := fmt.Sprintf("utilization policy, %d players for %d%% target utilization", players, gsd.Spec.Scaling.TargetUtilizationPercent)
+	return r.scaleTo(ctx, gsd, children, target, why, used, set)
+}
+
+// scaleEvent records a ScalingUp or ScalingDown Event when the fleet's
+// current servers went from one count to another; why carries the numbers
+// behind the decision.
+func (r *GSDeploymentReconciler) scaleEvent(gsd *gamev1alpha1.GSDeployment, from, to int32, why string) {
+	switch {
+	case to > from:
+		r.eventf(gsd, corev1.EventTypeNormal, "ScalingUp", "Scaled up from %d to %d GameServers: %s", from, to, why)
+	case to < from:
+		r.eventf(gsd, corev1.EventTypeNormal, "ScalingDown", "Scaled down from %d to %d GameServers: %s", from, to, why)
+	}
+}
+
+// webhookTarget asks the fleet's scaling webhook for the desired replica count.
+func (r *GSDeploymentReconciler) webhookTarget(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	children []gamev1alpha1.GameServer) (int32, error) {
+	req := autoscaler.Request{
+		UID:         uuid.NewUUID(),
+		Name:        gsd.Name,
+		Namespace:   gsd.Namespace,
+		Replicas:    int32(len(children)),
+		MinReplicas: gsd.Spec.MinReplicas,
+		MaxReplicas: gsd.Spec.MaxReplicas,
+		Servers:     make([]autoscaler.Server, 0, len(children)),
+	}
+	for _, gs := range children {
+		srv := autoscaler.Server{
+			Name:       gs.Name,
+			State:      string(gs.Status.State),
+			Players:    gs.Status.Players,
+			MaxPlayers: gs.Status.MaxPlayers,
+			Draining:   isDraining(&gs),
+			Allocated:  isAllocated(&gs),
+		}
+		if isReady(&gs) {
+			req.ReadyReplicas++
+		}
+		if srv.Draining {
+			req.DrainingReplicas++
+		}
+		if srv.Allocated {
+			req.AllocatedReplicas++
+		}
+		req.Servers = append(req.Servers, srv)
+	}
+	return r.scaleHooks.Desired(ctx, gsd.Spec.Scaling.Webhook, req)
+}
+
+// scaleTo creates or removes as many servers as needed in one pass so the
+// fleet has target current (non-draining) servers, clamped to maxReplicas and
+// minActive.
+// scaling.behavior and scaling.maxScaleUpStep may hold part of the change
+// back. Draining servers are left to the rollout; they only count against
+// maxReplicas. Only available servers are ever removed. It returns the target
+// it moved toward; why explains the target in the scaling Event.
+func (r *GSDeploymentReconciler) scaleTo(ctx context.Context, gsd *gamev1alpha1.GSDeployment,
+	children []gamev1alpha1.GameServer, target int32, why string, used *portPool,
+	set *gamev1alpha1.GameServerSet) ([]gamev1alpha1.GameServer, int32, error) {
+	var active int32
+	var available []gamev1alpha1.GameServer
+	for _, gs := range children {
+		if isDraining(&gs) {
+			continue
+		}
+		active++
+		if isAvailable(&gs) {
+			available = append(available, gs)
+		}
+	}
+	target = maxInt32(minInt32(target, gsd.Spec.MaxReplicas), minActive(gsd, children))
+	target = r.behave(gsd, active, target)
+	from := active
+	defer func() { r.scaleEvent(gsd, from, active, fmt.Sprintf("%s, target %d", why, target)) }()
+
+	total := int32(len(children))
+	limit := target
+	if gsd.Spec.Scaling != nil && gsd.Spec.Scaling.MaxScaleUpStep != nil {
+		limit = minInt32(limit, active+*gsd.Spec.Scaling.MaxScaleUpStep)
+	}
+	for ; active < limit && total < gsd.Spec.MaxReplicas; active++ {
+		newGS, err := r.createChild(ctx, gsd, set, used)
+		if err != nil {
+			return children, target, err
+		}
+		if newGS == nil {
+			break
+		}
+		r.recordScale(gsd, 1)
+		children = append(children, *newGS)
+		total++
+	}
+
+	if active > target {
+		// Cheapest first: servers that never became Ready, then the oldest.
+		sort.Slice(available, func(i, j int) bool {
+			ri, rj := isReady(&available[i]), isReady(&available[j])
+			if ri != rj {
+				return !ri
+			}
+			return available[i].CreationTimestamp.Before(&available[j].CreationTimestamp)
+		})
+		for _, gs := range available {
+			if active <= target {
+				break
+			}
+			if err := r.Delete(ctx, &gs); err != nil && !kerrors.IsNotFound(err) {
+				return children, target, err
+			}
+			scaleActions.WithLabelValues(gsd.Namespace, gsd.Name, "down").Inc()
+			r.recordScale(gsd, -1)
+			children = removeGS(children, gs.Name)
+			active--
+		}
+	}
+	return children, target, nil
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/autoscaler"
+)
+
+// fleetServer is a child GameServer of the "fleet" GSDeployment for pure scaling specs.
+func fleetServer(i int, state gamev1alpha1.GameServerState, players int32, annos ...string) gamev1alpha1.GameServer {
+	gs := gamev1alpha1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("fleet-%d", 30000+i),
+			Namespace: "default",
+			Labels:    childLabels("fleet"),
+		},
+		Spec:   gamev1alpha1.GameServerSpec{Port: int32(30000 + i)},
+		Status: gamev1alpha1.GameServerStatus{State: state, Players: players, MaxPlayers: 10},
+	}
+	if len(annos) > 0 {
+		gs.Annotations = map[string]string{}
+		for _, a := range annos {
+			gs.Annotations[a] = "true"
+		}
+	}
+	return gs
+}
+
+// fleetSet is the current GameServerSet of the "fleet" GSDeployment.
+func fleetSet() *gamev1alpha1.GameServerSet {
+	return &gamev1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fleet-rev1", Namespace: "default", UID: "set-uid"},
+		Spec:       gamev1alpha1.GameServerSetSpec{Revision: "rev1"},
+	}
+}
+
+// newScalingReconciler returns a reconciler backed by a fake client holding servers.
+func newScalingReconciler(servers []gamev1alpha1.GameServer) (*GSDeploymentReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+	b := fake.NewClientBuilder().WithScheme(scheme)
+	for i := range servers {
+		b = b.WithObjects(&servers[i])
+	}
+	c := b.Build()
+	return &GSDeploymentReconciler{Client: c, Scheme: scheme, scaleHooks: &autoscaler.Client{}}, c
+}
+
+func bufferFleet(size intstr.IntOrString, minR, maxR int32) *gamev1alpha1.GSDeployment {
+	return &gamev1alpha1.GSDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "fleet", Namespace: "default", UID: "fleet-uid"},
+		Spec: gamev1alpha1.GSDeploymentSpec{
+			MinReplicas: minR,
+			MaxReplicas: maxR,
+			PortRange:   gamev1alpha1.PortRange{Start: 30000, End: 30099},
+			Scaling:     &gamev1alpha1.Scaling{Policy: gamev1alpha1.ScalingPolicyBuffer, BufferSize: &size},
+		},
+	}
+}
+
+var _ = Describe("bufferTarget", func() {
+	It("adds an absolute buffer to the busy servers", func() {
+		size := intstr.FromInt32(3)
+		Expect(bufferTarget(&size, 5)).To(Equal(int32(8)))
+		Expect(bufferTarget(nil, 5)).To(Equal(int32(5 + defaultBufferSize)))
+	})
+
+	It("treats a percentage as the free share of the whole fleet", func() {
+		size := intstr.FromString("20%")
+		Expect(bufferTarget(&size, 8)).To(Equal(int32(10)))
+		Expect(bufferTarget(&size, 9)).To(Equal(int32(12))) // 11.25 rounds up
+		Expect(bufferTarget(&size, 0)).To(Equal(int32(0)))
+	})
+})
+
+var _ = Describe("scaleBuffer", func() {
+	ctx := context.Background()
+
+	count := func(c client.Client) int {
+		var list gamev1alpha1.GameServerList
+		Expect(c.List(ctx, &list)).To(Succeed())
+		return len(list.Items)
+	}
+
+	It("creates several servers in one pass to refill the buffer", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 4),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 2),
+			fleetServer(2, gamev1alpha1.GameServerStateReady, 0, allocatedAnno),
+		}
+		r, c := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(3), 1, 10)
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		for _, gs := range servers {
+			pool.take(gs.Spec.Port)
+		}
+
+		out, _, err := r.scaleBuffer(ctx, gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HaveLen(6))
+		Expect(count(c)).To(Equal(6))
+	})
+
+	It("removes surplus empty servers, never busy or allocated ones", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 4),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 0, allocatedAnno),
+			fleetServer(2, gamev1alpha1.GameServerStateReady, 0),
+			fleetServer(3, gamev1alpha1.GameServerStateReady, 0),
+			fleetServer(4, gamev1alpha1.GameServerStateStarting, 0),
+			fleetServer(5, gamev1alpha1.GameServerStateReady, 0),
+		}
+		r, c := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(1), 1, 10)
+
+		out, _, err := r.scaleBuffer(ctx, gsd, servers, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HaveLen(3))
+		Expect(count(c)).To(Equal(3))
+		names := []string{}
+		for _, gs := range out {
+			names = append(names, gs.Name)
+		}
+		// The Pending one goes first, then the oldest idle ones.
+		Expect(names).To(ContainElements("fleet-30000", "fleet-30001"))
+		Expect(names).NotTo(ContainElement("fleet-30004"))
+	})
+
+	It("keeps reserved servers out of the buffer and never removes them", func() {
+		reserved := fleetServer(1, gamev1alpha1.GameServerStateReady, 0)
+		reserved.Annotations = map[string]string{
+			gamev1alpha1.ReservedUntilAnnotation: time.Now().Add(time.Minute).UTC().Format(time.RFC3339),
+		}
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 0), reserved}
+		r, c := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(0), 0, 10)
+
+		out, _, err := r.scaleBuffer(ctx, gsd, servers, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ConsistOf(HaveField("Name", "fleet-30001")))
+		Expect(count(c)).To(Equal(1))
+	})
+
+	It("stays within maxReplicas and counts draining servers against it", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 4),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 3, drainAnno),
+		}
+		r, _ := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(5), 0, 4)
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		pool.take(30000)
+		pool.take(30001)
+
+		out, _, err := r.scaleBuffer(ctx, gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HaveLen(4))
+	})
+})
+
+var _ = Describe("scaling events", func() {
+	ctx := context.Background()
+
+	drain := func(recorder *record.FakeRecorder) []string {
+		close(recorder.Events)
+		var events []string
+		for e := range recorder.Events {
+			events = append(events, e)
+		}
+		return events
+	}
+
+	It("explains a scale-up with the numbers behind it", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 4, allocatedAnno),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 0),
+		}
+		r, _ := newScalingReconciler(servers)
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+		gsd := bufferFleet(intstr.FromInt32(3), 1, 10)
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		for _, gs := range servers {
+			pool.take(gs.Spec.Port)
+		}
+
+		_, _, err := r.scaleBuffer(ctx, gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drain(recorder)).To(ConsistOf(
+			"Normal ScalingUp Scaled up from 2 to 4 GameServers: buffer policy, 1 busy and 1 available servers, target 4"))
+	})
+
+	It("names the idle servers behind a threshold scale-down", func() {
+		idle := fleetServer(1, gamev1alpha1.GameServerStateReady, 0)
+		idle.Status.ZeroSince = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 5), idle}
+		r, _ := newScalingReconciler(servers)
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 10)
+		gsd.Spec.Scaling = nil
+		gsd.Spec.ScaleUpThresholdPercent, gsd.Spec.ScaleDownZeroSeconds = 80, 60
+
+		_, _, err := r.scaleThreshold(ctx, gsd, servers, newPortPool(gsd.Spec.PortRange, 1), fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drain(recorder)).To(ConsistOf(
+			"Normal ScalingDown Scaled down from 2 to 1 GameServers: threshold policy, target 1: 1 servers empty for 60s or more"))
+	})
+
+	It("warns once per pass when the port range runs out", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 4)}
+		r, _ := newScalingReconciler(servers)
+		recorder := record.NewFakeRecorder(10)
+		r.Recorder = recorder
+		gsd := bufferFleet(intstr.FromInt32(3), 1, 10)
+		gsd.Spec.PortRange = gamev1alpha1.PortRange{Start: 30000, End: 30001}
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		pool.take(30000)
+
+		_, _, err := r.scaleBuffer(ctx, gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drain(recorder)).To(ConsistOf(
+			"Warning PortRangeExhausted No free port in 30000-30001 for a new GameServer: 2 servers on 2 ports, 1 per port",
+			"Normal ScalingUp Scaled up from 1 to 2 GameServers: buffer policy, 1 busy and 0 available servers, target 4",
+		))
+	})
+})
+
+var _ = Describe("utilizationTarget", func() {
+	utilFleet := func(pct int32) *gamev1alpha1.GSDeployment {
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 20)
+		gsd.Spec.Scaling = &gamev1alpha1.Scaling{Policy: gamev1alpha1.ScalingPolicyUtilization, TargetUtilizationPercent: pct}
+		return gsd
+	}
+
+	It("sizes by fleet-wide utilization, not the fullest server", func() {
+		// One full server and nine empty ones: 10/100 slots in use.
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 10)}
+		for i := 1; i < 10; i++ {
+			servers = append(servers, fleetServer(i, gamev1alpha1.GameServerStateReady, 0))
+		}
+		n, ok := utilizationTarget(utilFleet(50), servers)
+		Expect(ok).To(BeTrue())
+		Expect(n).To(Equal(int32(2)))
+	})
+
+	It("ignores draining servers and rounds up", func() {
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 9),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 8),
+			fleetServer(2, gamev1alpha1.GameServerStateReady, 10, drainAnno),
+		}
+		// 17 players at 70% of 10 slots → 2.43 → 3.
+		n, _ := utilizationTarget(utilFleet(0), servers)
+		Expect(n).To(Equal(int32(3)))
+	})
+
+	It("falls back to parameters.maxPlayers, then holds", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateStarting, 0), fleetServer(1, gamev1alpha1.GameServerStateStarting, 0)}
+		for i := range servers {
+			servers[i].Status.MaxPlayers = 0
+		}
+		gsd := utilFleet(50)
+		n, ok := utilizationTarget(gsd, servers)
+		Expect(ok).To(BeFalse())
+		Expect(n).To(Equal(int32(2)))
+
+		gsd.Spec.Parameters = &gamev1alpha1.Parameters{MaxPlayers: ptr.To[int32](16)}
+		n, ok = utilizationTarget(gsd, servers)
+		Expect(ok).To(BeTrue())
+		Expect(n).To(Equal(int32(0)))
+	})
+
+	It("adds several servers per pass, up to maxScaleUpStep", func() {
+		servers := []gamev1alpha1.GameServer{fleetServer(0, gamev1alpha1.GameServerStateReady, 10), fleetServer(1, gamev1alpha1.GameServerStateReady, 10)}
+		r, _ := newScalingReconciler(servers)
+		gsd := utilFleet(25) // 20 players at 2.5 per server → 8
+		pool := newPortPool(gsd.Spec.PortRange, 1)
+		pool.take(30000)
+		pool.take(30001)
+
+		out, desired, err := r.scaleUtilization(context.Background(), gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(desired).To(Equal(int32(8)))
+		Expect(out).To(HaveLen(8))
+
+		gsd.Spec.Scaling.MaxScaleUpStep = ptr.To[int32](2)
+		out, desired, err = r.scaleUtilization(context.Background(), gsd, servers, pool, fleetSet())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(desired).To(Equal(int32(8)))
+		Expect(out).To(HaveLen(4))
+	})
+})
+
+var _ = Describe("applySchedule", func() {
+	It("overrides only the fields the window sets", func() {
+		gsd := bufferFleet(intstr.FromInt32(2), 1, 10)
+		peak := intstr.FromString("30%")
+		applySchedule(gsd, &gamev1alpha1.ScalingSchedule{Name: "evening", MinReplicas: ptr.To[int32](6), BufferSize: &peak})
+		Expect(gsd.Spec.MinReplicas).To(Equal(int32(6)))
+		Expect(gsd.Spec.MaxReplicas).To(Equal(int32(10)))
+		Expect(gsd.Spec.Scaling.BufferSize.String()).To(Equal("30%"))
+	})
+
+	It("keeps minReplicas within maxReplicas", func() {
+		gsd := bufferFleet(intstr.FromInt32(2), 4, 10)
+		applySchedule(gsd, &gamev1alpha1.ScalingSchedule{Name: "night", MaxReplicas: ptr.To[int32](3)})
+		Expect(gsd.Spec.MinReplicas).To(Equal(int32(3)))
+		Expect(gsd.Spec.MaxReplicas).To(Equal(int32(3)))
+	})
+
+	It("leaves the spec alone without a window", func() {
+		gsd := bufferFleet(intstr.FromInt32(2), 1, 10)
+		applySchedule(gsd, nil)
+		Expect(gsd.Spec.MinReplicas).To(Equal(int32(1)))
+	})
+})
+
+var _ = Describe("webhookTarget", func() {
+	It("sends the fleet review and returns the answer", func() {
+		var got autoscaler.Request
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rev autoscaler.Review
+			Expect(json.NewDecoder(r.Body).Decode(&rev)).To(Succeed())
+			got = *rev.Request
+			_ = json.NewEncoder(w).Encode(autoscaler.Review{Response: &autoscaler.Response{UID: got.UID, Replicas: 9}})
+		}))
+		defer srv.Close()
+
+		servers := []gamev1alpha1.GameServer{
+			fleetServer(0, gamev1alpha1.GameServerStateReady, 4),
+			fleetServer(1, gamev1alpha1.GameServerStateReady, 0, drainAnno),
+			fleetServer(2, gamev1alpha1.GameServerStateStarting, 0),
+		}
+		r, _ := newScalingReconciler(servers)
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 10)
+		gsd.Spec.Scaling = &gamev1alpha1.Scaling{
+			Policy:  gamev1alpha1.ScalingPolicyWebhook,
+			Webhook: &admissionregistrationv1.WebhookClientConfig{URL: &srv.URL},
+		}
+
+		n, err := r.webhookTarget(context.Background(), gsd, servers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int32(9)))
+		Expect(got.Replicas).To(Equal(int32(3)))
+		Expect(got.ReadyReplicas).To(Equal(int32(2)))
+		Expect(got.DrainingReplicas).To(Equal(int32(1)))
+		Expect(got.Servers).To(ContainElement(autoscaler.Server{Name: "fleet-30000", State: "Ready", Players: 4, MaxPlayers: 10}))
+	})
+
+	It("fails when the webhook cannot be reached", func() {
+		r, _ := newScalingReconciler(nil)
+		gsd := bufferFleet(intstr.FromInt32(0), 1, 10)
+		url := "http://127.0.0.1:1/scale"
+		gsd.Spec.Scaling = &gamev1alpha1.Scaling{
+			Policy:  gamev1alpha1.ScalingPolicyWebhook,
+			Webhook: &admissionregistrationv1.WebhookClientConfig{URL: &url},
+		}
+		_, err := r.webhookTarget(context.Background(), gsd, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,26 @@
+package heartbeat
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Outcomes of a heartbeat request.
+const (
+	resultOK           = "ok"
+	resultUnauthorized = "unauthorized"
+	resultBadRequest   = "bad_request"
+	resultNotFound     = "not_found"
+	resultDisabled     = "disabled"
+	resultRateLimited  = "rate_limited"
+	resultError        = "error"
+)
+
+var heartbeats = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gameserver_operator", Name: "heartbeats_total",
+	Help: "Heartbeat requests by result (ok, unauthorized, bad_request, not_found, disabled, rate_limited, error).",
+}, []string{"result"})
+
+func init() {
+	metrics.Registry.MustRegister(heartbeats)
+}
@@ -0,0 +1,297 @@
+// Package heartbeat hosts the endpoint game servers push their state to, as an
+// alternative (or complement) to the controller polling them.
+//
+// A server POSTs {"players","maxPlayers","ready"} to $HEARTBEAT_URL with
+// "Authorization: Bearer $HEARTBEAT_TOKEN". Both variables are injected into
+// the game container when the GameServer's statusMode is Push or Both. When
+// the endpoint serves TLS, $HEARTBEAT_CA holds the CA to verify it with.
+package heartbeat
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"golang.org/x/time/rate"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultTimeout is the staleness timeout when heartbeatTimeoutSeconds is unset.
+const DefaultTimeout = 30 * time.Second
+
+// Defaults for the per-GameServer status write limit.
+const (
+	DefaultRate  = rate.Limit(1) // writes per second
+	DefaultBurst = 5
+)
+
+// Message is the heartbeat body.
+type Message struct {
+	Players    int32 `json:"players"`
+	MaxPlayers int32 `json:"maxPlayers"`
+	// Ready defaults to true; false keeps the server out of allocation (state RequestReady).
+	Ready *bool `json:"ready,omitempty"`
+}
+
+// Server is a manager.Runnable serving heartbeats on Addr.
+type Server struct {
+	Client client.Client
+	Addr   string
+	// CertDir, if set, holds tls.crt and tls.key; heartbeats are then served
+	// over TLS, picking up renewed certificates as they are written.
+	CertDir string
+	// Status writes allowed per GameServer; zero values mean DefaultRate/DefaultBurst.
+	Rate  rate.Limit
+	Burst int
+
+	mu       sync.Mutex
+	limiters map[types.NamespacedName]*limiterEntry
+	now      func() time.Time
+}
+
+type limiterEntry struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// Timeout returns the staleness timeout of a GameServer.
+func Timeout(gs *gamev1alpha1.GameServer) time.Duration {
+	if gs.Spec.HeartbeatTimeoutSeconds > 0 {
+		return time.Duration(gs.Spec.HeartbeatTimeoutSeconds) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Enabled reports whether a GameServer accepts heartbeats.
+func Enabled(gs *gamev1alpha1.GameServer) bool {
+	return gs.Spec.StatusMode == gamev1alpha1.StatusModePush || gs.Spec.StatusMode == gamev1alpha1.StatusModeBoth
+}
+
+// Start serves until ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/heartbeat/{namespace}/{name}", s)
+	srv := &http.Server{Addr: s.Addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	serve := srv.ListenAndServe
+	if s.CertDir != "" {
+		watcher, err := certwatcher.New(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				ctrllog.FromContext(ctx).Error(err, "heartbeat certificate watcher stopped")
+			}
+		}()
+		srv.TLSConfig = &tls.Config{GetCertificate: watcher.GetCertificate, MinVersion: tls.VersionTLS12}
+		serve = func() error { return srv.ListenAndServeTLS("", "") }
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- serve() }()
+
+	sweep := time.NewTicker(time.Minute)
+	defer sweep.Stop()
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case <-sweep.C:
+			s.forgetIdle(10 * time.Minute)
+		case <-ctx.Done():
+			shutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutCtx); err != nil {
+				return err
+			}
+			if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection is false: any replica may accept heartbeats.
+func (s *Server) NeedLeaderElection() bool { return false }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	key := types.NamespacedName{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}
+
+	// The Pod is the credential holder: its annotation carries the token hash,
+	// so a restarted Pod with a new token invalidates the old one.
+	var pod corev1.Pod
+	if err := s.Client.Get(ctx, key, &pod); err != nil && !kerrors.IsNotFound(err) {
+		s.fail(w, resultError, http.StatusInternalServerError, err.Error())
+		return
+	} else if err != nil || !tokenMatches(bearer(r), pod.Annotations[TokenHashAnnotation]) {
+		s.fail(w, resultUnauthorized, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&msg); err != nil {
+		s.fail(w, resultBadRequest, http.StatusBadRequest, err.Error())
+		return
+	}
+	if msg.Players < 0 || msg.MaxPlayers < 0 {
+		s.fail(w, resultBadRequest, http.StatusBadRequest, "players and maxPlayers must not be negative")
+		return
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var gs gamev1alpha1.GameServer
+		if err := s.Client.Get(ctx, key, &gs); err != nil {
+			return err
+		}
+		if !Enabled(&gs) {
+			return errDisabled
+		}
+		old := gs.Status.DeepCopy()
+		s.apply(&gs, &pod, msg)
+		if equality.Semantic.DeepEqual(*old, gs.Status) {
+			return nil
+		}
+		if !s.allow(key) {
+			return errRateLimited
+		}
+		return s.Client.Status().Update(ctx, &gs)
+	})
+	switch {
+	case err == nil:
+		heartbeats.WithLabelValues(resultOK).Inc()
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, errDisabled):
+		s.fail(w, resultDisabled, http.StatusForbidden, err.Error())
+	case errors.Is(err, errRateLimited):
+		w.Header().Set("Retry-After", "1")
+		s.fail(w, resultRateLimited, http.StatusTooManyRequests, err.Error())
+	case kerrors.IsNotFound(err):
+		s.fail(w, resultNotFound, http.StatusNotFound, "gameserver not found")
+	default:
+		ctrllog.FromContext(ctx).Error(err, "writing heartbeat", "gameserver", key)
+		s.fail(w, resultError, http.StatusInternalServerError, err.Error())
+	}
+}
+
+var (
+	errDisabled    = errors.New("statusMode does not accept heartbeats")
+	errRateLimited = errors.New("too many status updates")
+)
+
+// apply folds a report into the GameServer's status. LastHeartbeat is only
+// refreshed every third of the timeout so an unchanged server does not cause
+// a status write per heartbeat.
+func (s *Server) apply(gs *gamev1alpha1.GameServer, pod *corev1.Pod, msg Message) {
+	now := metav1.NewTime(s.clock())
+	st := &gs.Status
+
+	st.Players = msg.Players
+	st.MaxPlayers = msg.MaxPlayers
+	st.PlayersUnknown = false
+	st.NodeName = pod.Spec.NodeName
+	if msg.Players == 0 {
+		if st.ZeroSince == nil {
+			st.ZeroSince = &now
+		}
+	} else {
+		st.ZeroSince = nil
+	}
+
+	// With Both, polling owns State and Reachable; pushes only add counts.
+	if gs.Spec.StatusMode == gamev1alpha1.StatusModePush {
+		switch {
+		case msg.Ready != nil && !*msg.Ready:
+			st.SetState(gamev1alpha1.GameServerStateRequestReady)
+		case st.State == gamev1alpha1.GameServerStateReserved || st.State == gamev1alpha1.GameServerStateAllocated:
+			// Held by the controller; a heartbeat only confirms it is alive.
+		default:
+			st.SetState(gamev1alpha1.GameServerStateReady)
+		}
+		meta.SetStatusCondition(&st.Conditions, metav1.Condition{
+			Type:               "Reachable",
+			Status:             metav1.ConditionTrue,
+			Reason:             "Heartbeat",
+			Message:            "Heartbeat received",
+			ObservedGeneration: gs.Generation,
+		})
+	}
+
+	if st.LastHeartbeat == nil || now.Sub(st.LastHeartbeat.Time) >= Timeout(gs)/3 {
+		st.LastHeartbeat = &now
+	}
+}
+
+func (s *Server) allow(key types.NamespacedName) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limiters == nil {
+		s.limiters = map[types.NamespacedName]*limiterEntry{}
+	}
+	e, ok := s.limiters[key]
+	if !ok {
+		r, b := s.Rate, s.Burst
+		if r == 0 {
+			r = DefaultRate
+		}
+		if b == 0 {
+			b = DefaultBurst
+		}
+		e = &limiterEntry{lim: rate.NewLimiter(r, b)}
+		s.limiters[key] = e
+	}
+	e.seen = s.clock()
+	return e.lim.AllowN(e.seen, 1)
+}
+
+// forgetIdle drops limiters of servers that stopped sending heartbeats.
+func (s *Server) forgetIdle(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.clock().Add(-idle)
+	for k, e := range s.limiters {
+		if e.seen.Before(cutoff) {
+			delete(s.limiters, k)
+		}
+	}
+}
+
+func (s *Server) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Server) fail(w http.ResponseWriter, result string, code int, msg string) {
+	heartbeats.WithLabelValues(result).Inc()
+	http.Error(w, msg, code)
+}
+
+func bearer(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if t, ok := strings.CutPrefix(h, "Bearer "); ok {
+		return strings.TrimSpace(t)
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heartbeat
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("Server", func() {
+	const ns, name = "games", "gs-1"
+	var (
+		c     client.Client
+		srv   *Server
+		token string
+		now   time.Time
+	)
+
+	post := func(tok, body string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.Handle("POST /v1/heartbeat/{namespace}/{name}", srv)
+		req := httptest.NewRequest(http.MethodPost, Path(ns, name), strings.NewReader(body))
+		if tok != "" {
+			req.Header.Set("Authorization", "Bearer "+tok)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	current := func() gamev1alpha1.GameServer {
+		var gs gamev1alpha1.GameServer
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: ns, Name: name}, &gs)).To(Succeed())
+		return gs
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+
+		var hash string
+		var err error
+		token, hash, err = NewToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		gs := &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30000, StatusMode: gamev1alpha1.StatusModePush},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns,
+				Annotations: map[string]string{TokenHashAnnotation: hash}},
+			Spec: corev1.PodSpec{NodeName: "node-a"},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(gs, pod).WithStatusSubresource(gs).Build()
+		now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		srv = &Server{Client: c, Burst: 2, now: func() time.Time { return now }}
+	})
+
+	It("writes the reported state into the GameServer status", func() {
+		rec := post(token, `{"players":4,"maxPlayers":16}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		gs := current()
+		Expect(gs.Status.Players).To(Equal(int32(4)))
+		Expect(gs.Status.MaxPlayers).To(Equal(int32(16)))
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateReady))
+		Expect(gs.Status.NodeName).To(Equal("node-a"))
+		Expect(gs.Status.LastHeartbeat.Time).To(BeTemporally("==", now))
+	})
+
+	It("reports a not-ready server as RequestReady and tracks zeroSince", func() {
+		Expect(post(token, `{"players":0,"maxPlayers":16,"ready":false}`).Code).To(Equal(http.StatusNoContent))
+		gs := current()
+		Expect(gs.Status.State).To(Equal(gamev1alpha1.GameServerStateRequestReady))
+		Expect(gs.Status.ZeroSince).NotTo(BeNil())
+	})
+
+	It("rejects a missing or wrong token", func() {
+		Expect(post("", `{"players":1}`).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post("nope", `{"players":1}`).Code).To(Equal(http.StatusUnauthorized))
+		Expect(current().Status.Players).To(BeZero())
+	})
+
+	It("rejects heartbeats when the GameServer polls", func() {
+		gs := current()
+		gs.Spec.StatusMode = gamev1alpha1.StatusModePoll
+		Expect(c.Update(context.Background(), &gs)).To(Succeed())
+
+		Expect(post(token, `{"players":1}`).Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("leaves the state to the poller in Both mode", func() {
+		gs := current()
+		gs.Spec.StatusMode = gamev1alpha1.StatusModeBoth
+		Expect(c.Update(context.Background(), &gs)).To(Succeed())
+
+		Expect(post(token, `{"players":2,"maxPlayers":8}`).Code).To(Equal(http.StatusNoContent))
+		gs = current()
+		Expect(gs.Status.Players).To(Equal(int32(2)))
+		Expect(gs.Status.State).To(BeEmpty())
+	})
+
+	It("rate limits status writes but not unchanged heartbeats", func() {
+		Expect(post(token, `{"players":1}`).Code).To(Equal(http.StatusNoContent))
+		Expect(post(token, `{"players":2}`).Code).To(Equal(http.StatusNoContent))
+		// Same state again: nothing to write, so the limiter is not consulted.
+		Expect(post(token, `{"players":2}`).Code).To(Equal(http.StatusNoContent))
+
+		rec := post(token, `{"players":3}`)
+		Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rec.Header().Get("Retry-After")).To(Equal("1"))
+
+		now = now.Add(time.Second)
+		Expect(post(token, `{"players":3}`).Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("refreshes lastHeartbeat only every third of the timeout", func() {
+		Expect(post(token, `{"players":1}`).Code).To(Equal(http.StatusNoContent))
+		first := current().Status.LastHeartbeat.Time
+
+		now = now.Add(5 * time.Second)
+		Expect(post(token, `{"players":1}`).Code).To(Equal(http.StatusNoContent))
+		Expect(current().Status.LastHeartbeat.Time).To(BeTemporally("==", first))
+
+		now = now.Add(5 * time.Second)
+		Expect(post(token, `{"players":1}`).Code).To(Equal(http.StatusNoContent))
+		Expect(current().Status.LastHeartbeat.Time).To(BeTemporally("==", now))
+	})
+
+	It("serves over TLS with the certificate in CertDir", func() {
+		dir := GinkgoT().TempDir()
+		pool := writeServingCert(dir)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		srv.Addr = l.Addr().String()
+		Expect(l.Close()).To(Succeed())
+		srv.CertDir = dir
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- srv.Start(ctx) }()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+
+		hc := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		Eventually(func() (int, error) {
+			req, err := http.NewRequest(http.MethodPost, "https://"+srv.Addr+Path(ns, name), strings.NewReader(`{"players":2}`))
+			if err != nil {
+				return 0, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := hc.Do(req)
+			if err != nil {
+				return 0, err
+			}
+			defer func() { _ = resp.Body.Close() }()
+			return resp.StatusCode, nil
+		}).Should(Equal(http.StatusNoContent))
+		Expect(current().Status.Players).To(Equal(int32(2)))
+	})
+})
+
+// writeServingCert puts a self-signed certificate for 127.0.0.1 into dir as
+// tls.crt/tls.key and returns a pool trusting it.
+func writeServingCert(dir string) *x509.CertPool {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "heartbeat"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return pool
+}
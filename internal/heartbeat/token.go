@@ -0,0 +1,57 @@
+package heartbeat
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// Per-pod credentials. The raw token is only ever kept in a Secret the game
+// container's environment refers to; the Pod carries its SHA-256 so the
+// server can check a heartbeat without reading secrets.
+const (
+	TokenHashAnnotation = "game.example.com/heartbeat-token-sha256"
+	TokenEnv            = "HEARTBEAT_TOKEN"
+	URLEnv              = "HEARTBEAT_URL"
+	// TokenSecretKey is the key of the token in the GameServer's Secret.
+	TokenSecretKey = "token"
+	// With a TLS endpoint the Secret also carries the CA the game server
+	// verifies it against, as PEM.
+	CAEnv       = "HEARTBEAT_CA"
+	CASecretKey = "ca.crt"
+)
+
+// SecretName is the name of the Secret holding the token of a GameServer's
+// current Pod.
+func SecretName(gameServer string) string {
+	return gameServer + "-heartbeat"
+}
+
+// NewToken returns a fresh random token and its hash.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken is the value stored under TokenHashAnnotation.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenMatches(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// Path returns the heartbeat URL path for a GameServer.
+func Path(namespace, name string) string {
+	return "/v1/heartbeat/" + namespace + "/" + name
+}
@@ -0,0 +1,29 @@
+package poller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "gameserver_operator"
+
+var (
+	pollDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace, Name: "status_poll_duration_seconds",
+		Help:    "Latency of GameServer status polls.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2},
+	}, []string{"result"})
+	pollFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace, Name: "status_poll_failures_total",
+		Help: "Failed GameServer status polls by reason (see probe.Reason*).",
+	}, []string{"reason"})
+	pollLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace, Name: "status_poll_lag_seconds",
+		Help:    "How late a poll started relative to its schedule; grows when the worker pool is saturated.",
+		Buckets: []float64{.001, .01, .1, .5, 1, 2, 5, 10, 30},
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(pollDuration, pollFailures, pollLag)
+}
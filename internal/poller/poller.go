@@ -0,0 +1,297 @@
+// Package poller runs GameServer status probes off the reconcile path.
+//
+// The GameServer controller registers each pollable server with Track. A
+// scheduler hands due targets to a bounded worker pool; every result is kept
+// as the target's latest Observation and announced on Events, which the
+// controller watches as a channel source and turns into status updates.
+package poller
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/probe"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Defaults used when the Poller fields are zero.
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultWorkers  = 32
+)
+
+// Target is what the poller needs to probe one GameServer.
+type Target struct {
+	Host     string
+	Port     int32
+	Probe    *gamev1alpha1.StatusProbe
+	PollPath string
+}
+
+// Observation is the outcome of one probe.
+type Observation struct {
+	Result   probe.Result
+	Err      error
+	Endpoint string
+	At       time.Time
+}
+
+// Poller is a manager.Runnable. Targets are polled every Interval, give or
+// take Jitter, by at most Workers concurrent probes.
+type Poller struct {
+	Interval time.Duration
+	Workers  int
+	// Fraction of Interval each target's schedule is randomly shifted by (default 0.1),
+	// so servers created together do not stay in lockstep.
+	Jitter float64
+
+	once      sync.Once
+	mu        sync.Mutex
+	targets   map[types.NamespacedName]*entry
+	queue     dueQueue
+	wake      chan struct{}
+	events    chan event.GenericEvent
+	httpc     *http.Client
+	newProber func(*gamev1alpha1.StatusProbe, string, *http.Client) (probe.StatusProber, error)
+}
+
+type entry struct {
+	key       types.NamespacedName
+	target    Target
+	due       time.Time
+	index     int // position in queue; -1 while a worker has it
+	last      Observation
+	hasLast   bool
+	untracked bool
+}
+
+func (p *Poller) init() {
+	p.once.Do(func() {
+		if p.Interval <= 0 {
+			p.Interval = DefaultInterval
+		}
+		if p.Workers <= 0 {
+			p.Workers = DefaultWorkers
+		}
+		if p.Jitter <= 0 {
+			p.Jitter = 0.1
+		}
+		p.targets = map[types.NamespacedName]*entry{}
+		p.wake = make(chan struct{}, 1)
+		p.events = make(chan event.GenericEvent, p.Workers)
+		// Keep connections to every server alive between polls; with
+		// hostNetwork each server is its own host:port.
+		p.httpc = &http.Client{
+			Timeout: probe.DefaultTimeout,
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: probe.DefaultTimeout}).DialContext,
+				MaxIdleConnsPerHost: 1,
+				IdleConnTimeout:     3 * p.Interval,
+			},
+		}
+		if p.newProber == nil {
+			p.newProber = probe.New
+		}
+	})
+}
+
+// Events announces a new Observation; the object only carries name and namespace.
+func (p *Poller) Events() <-chan event.GenericEvent {
+	p.init()
+	return p.events
+}
+
+// Track starts polling key, or updates where it is polled. A new target gets
+// a random first slot within one Interval.
+func (p *Poller) Track(key types.NamespacedName, t Target) {
+	if p == nil {
+		return
+	}
+	p.init()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.targets[key]; ok {
+		e.target = t
+		return
+	}
+	e := &entry{key: key, target: t, index: -1}
+	e.due = time.Now().Add(time.Duration(rand.Int64N(int64(p.Interval))))
+	p.targets[key] = e
+	heap.Push(&p.queue, e)
+	p.poke()
+}
+
+// Untrack stops polling key and forgets its last Observation.
+func (p *Poller) Untrack(key types.NamespacedName) {
+	if p == nil {
+		return
+	}
+	p.init()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.targets[key]
+	if !ok {
+		return
+	}
+	e.untracked = true
+	if e.index >= 0 {
+		heap.Remove(&p.queue, e.index)
+	}
+	delete(p.targets, key)
+}
+
+// Last returns the latest Observation for key.
+func (p *Poller) Last(key types.NamespacedName) (Observation, bool) {
+	if p == nil {
+		return Observation{}, false
+	}
+	p.init()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.targets[key]; ok && e.hasLast {
+		return e.last, true
+	}
+	return Observation{}, false
+}
+
+// Start runs the scheduler and workers until ctx is cancelled.
+func (p *Poller) Start(ctx context.Context) error {
+	p.init()
+	jobs := make(chan *entry)
+	var wg sync.WaitGroup
+	for range p.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				p.poll(ctx, e)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		e, wait := p.next(time.Now())
+		if e != nil {
+			select {
+			case jobs <- e:
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-p.wake:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// next pops the earliest due target, or says how long to sleep.
+func (p *Poller) next(now time.Time) (*entry, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return nil, p.Interval
+	}
+	if wait := p.queue[0].due.Sub(now); wait > 0 {
+		return nil, wait
+	}
+	e := heap.Pop(&p.queue).(*entry)
+	pollLag.Observe(now.Sub(e.due).Seconds())
+	return e, 0
+}
+
+func (p *Poller) poll(ctx context.Context, e *entry) {
+	p.mu.Lock()
+	t := e.target
+	p.mu.Unlock()
+
+	obs := Observation{At: time.Now()}
+	prober, err := p.newProber(t.Probe, t.PollPath, p.httpc)
+	if err == nil {
+		obs.Endpoint = prober.Endpoint(t.Host, t.Port)
+		obs.Result, err = prober.Probe(ctx, t.Host, t.Port)
+	}
+	obs.Err = err
+	done := time.Now()
+	if err == nil {
+		pollDuration.WithLabelValues("success").Observe(done.Sub(obs.At).Seconds())
+	} else {
+		pollDuration.WithLabelValues("error").Observe(done.Sub(obs.At).Seconds())
+		pollFailures.WithLabelValues(probe.Reason(err)).Inc()
+	}
+
+	p.mu.Lock()
+	if e.untracked {
+		p.mu.Unlock()
+		return
+	}
+	e.last, e.hasLast = obs, true
+	j := time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(p.Interval))
+	e.due = done.Add(p.Interval + j)
+	heap.Push(&p.queue, e)
+	p.poke()
+	p.mu.Unlock()
+
+	gs := &gamev1alpha1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: e.key.Name, Namespace: e.key.Namespace}}
+	select {
+	case p.events <- event.GenericEvent{Object: gs}:
+	case <-ctx.Done():
+	}
+}
+
+// poke wakes the scheduler; callers hold p.mu.
+func (p *Poller) poke() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dueQueue is a min-heap of entries ordered by due time.
+type dueQueue []*entry
+
+func (q dueQueue) Len() int           { return len(q) }
+func (q dueQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q dueQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *dueQueue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+func (q *dueQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	e.index = -1
+	return e
+}
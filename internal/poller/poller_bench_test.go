@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/probe"
+)
+
+// BenchmarkFreshness tracks thousands of fake servers, a share of which hang
+// until the probe timeout, and reports how old each server's last observation
+// is after a few intervals. With enough workers the p99 age stays near one
+// Interval; a single worker (the old inline poll) falls far behind.
+//
+//	go test ./internal/poller -run '^$' -bench Freshness -benchtime 1x
+func BenchmarkFreshness(b *testing.B) {
+	const (
+		interval = 500 * time.Millisecond
+		rtt      = time.Millisecond
+		timeout  = 100 * time.Millisecond // probe.DefaultTimeout scaled like interval
+	)
+	for _, targets := range []int{1000, 5000} {
+		for _, workers := range []int{1, 64, 256} {
+			b.Run(fmt.Sprintf("targets=%d/workers=%d", targets, workers), func(b *testing.B) {
+				for range b.N {
+					runFreshness(b, targets, workers, interval, rtt, timeout)
+				}
+			})
+		}
+	}
+}
+
+// hangingProber answers after rtt, except every 50th host (2%) which times out.
+type hangingProber struct{ rtt, timeout time.Duration }
+
+func (h hangingProber) Probe(ctx context.Context, _ string, port int32) (probe.Result, error) {
+	d := h.rtt
+	if port%50 == 0 {
+		d = h.timeout
+	}
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+	return probe.Result{HasCounts: true}, nil
+}
+
+func (hangingProber) Endpoint(string, int32) string { return "" }
+
+func runFreshness(b *testing.B, targets, workers int, interval, rtt, timeout time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := &Poller{Interval: interval, Workers: workers}
+	p.newProber = func(*gamev1alpha1.StatusProbe, string, *http.Client) (probe.StatusProber, error) {
+		return hangingProber{rtt: rtt, timeout: timeout}, nil
+	}
+	go func() {
+		for {
+			select {
+			case <-p.Events():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() { _ = p.Start(ctx) }()
+
+	keys := make([]types.NamespacedName, targets)
+	tracked := time.Now()
+	for i := range keys {
+		keys[i] = types.NamespacedName{Namespace: "bench", Name: fmt.Sprintf("gs-%d", i)}
+		p.Track(keys[i], Target{Host: "127.0.0.1", Port: int32(i)})
+	}
+
+	time.Sleep(4 * interval)
+	now := time.Now()
+	ages := make([]float64, len(keys))
+	for i, k := range keys {
+		last := tracked
+		if obs, ok := p.Last(k); ok {
+			last = obs.At
+		}
+		ages[i] = now.Sub(last).Seconds() / interval.Seconds()
+	}
+	sort.Float64s(ages)
+	b.ReportMetric(ages[len(ages)/2], "p50-age/interval")
+	b.ReportMetric(ages[len(ages)*99/100], "p99-age/interval")
+	b.ReportMetric(ages[len(ages)-1], "max-age/interval")
+}
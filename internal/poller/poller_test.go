@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/probe"
+)
+
+// fakeProber answers after delay; hosts named "down" fail.
+type fakeProber struct {
+	delay             time.Duration
+	inFlight, maxSeen *atomic.Int32
+}
+
+func (f fakeProber) Probe(ctx context.Context, host string, port int32) (probe.Result, error) {
+	if f.inFlight != nil {
+		n := f.inFlight.Add(1)
+		defer f.inFlight.Add(-1)
+		for {
+			m := f.maxSeen.Load()
+			if n <= m || f.maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return probe.Result{}, ctx.Err()
+	}
+	if host == "down" {
+		return probe.Result{}, &probe.Error{Reason: probe.ReasonConnection, Err: errors.New("refused")}
+	}
+	return probe.Result{Players: port % 10, MaxPlayers: 10, HasCounts: true}, nil
+}
+
+func (f fakeProber) Endpoint(host string, port int32) string {
+	return fmt.Sprintf("fake://%s:%d", host, port)
+}
+
+func newFakePoller(interval time.Duration, workers int, f fakeProber) *Poller {
+	p := &Poller{Interval: interval, Workers: workers}
+	p.newProber = func(*gamev1alpha1.StatusProbe, string, *http.Client) (probe.StatusProber, error) {
+		return f, nil
+	}
+	return p
+}
+
+var _ = Describe("Poller", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(func() { cancel() })
+	})
+
+	It("polls tracked targets and announces each observation", func() {
+		p := newFakePoller(50*time.Millisecond, 2, fakeProber{})
+		go func() { _ = p.Start(ctx) }()
+
+		key := types.NamespacedName{Namespace: "games", Name: "gs-1"}
+		p.Track(key, Target{Host: "10.0.0.1", Port: 30003})
+
+		var ev = <-p.Events()
+		Expect(ev.Object.GetName()).To(Equal("gs-1"))
+		Expect(ev.Object.GetNamespace()).To(Equal("games"))
+		obs, ok := p.Last(key)
+		Expect(ok).To(BeTrue())
+		Expect(obs.Err).NotTo(HaveOccurred())
+		Expect(obs.Endpoint).To(Equal("fake://10.0.0.1:30003"))
+		Expect(obs.Result).To(Equal(probe.Result{Players: 3, MaxPlayers: 10, HasCounts: true}))
+
+		// and again one interval later
+		Eventually(p.Events()).WithTimeout(time.Second).Should(Receive())
+	})
+
+	It("keeps failures as observations", func() {
+		p := newFakePoller(50*time.Millisecond, 1, fakeProber{})
+		go func() { _ = p.Start(ctx) }()
+
+		key := types.NamespacedName{Namespace: "games", Name: "gs-down"}
+		p.Track(key, Target{Host: "down", Port: 30000})
+		Eventually(p.Events()).WithTimeout(time.Second).Should(Receive())
+		obs, _ := p.Last(key)
+		Expect(probe.Reason(obs.Err)).To(Equal(probe.ReasonConnection))
+	})
+
+	It("stops polling untracked targets", func() {
+		p := newFakePoller(20*time.Millisecond, 1, fakeProber{})
+		go func() { _ = p.Start(ctx) }()
+
+		key := types.NamespacedName{Namespace: "games", Name: "gs-1"}
+		p.Track(key, Target{Host: "10.0.0.1", Port: 30000})
+		Eventually(p.Events()).WithTimeout(time.Second).Should(Receive())
+		p.Untrack(key)
+
+		_, ok := p.Last(key)
+		Expect(ok).To(BeFalse())
+		Consistently(p.Events(), 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("never runs more probes at once than it has workers", func() {
+		var inFlight, maxSeen atomic.Int32
+		p := newFakePoller(10*time.Millisecond, 3, fakeProber{delay: 5 * time.Millisecond, inFlight: &inFlight, maxSeen: &maxSeen})
+		go func() { _ = p.Start(ctx) }()
+		go func() {
+			for range p.Events() {
+			}
+		}()
+
+		for i := range 50 {
+			p.Track(types.NamespacedName{Namespace: "games", Name: fmt.Sprintf("gs-%d", i)}, Target{Host: "10.0.0.1", Port: int32(i)})
+		}
+		Eventually(maxSeen.Load).Should(BeNumerically("==", 3))
+		Consistently(maxSeen.Load, 100*time.Millisecond).Should(BeNumerically("<=", 3))
+	})
+})
@@ -0,0 +1,111 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// A2SProber speaks the Valve A2S_INFO UDP query protocol
+// (https://developer.valvesoftware.com/wiki/Server_queries#A2S_INFO).
+type A2SProber struct{}
+
+var a2sInfoRequest = append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'T'}, []byte("Source Engine Query\x00")...)
+
+const (
+	a2sHeaderInfo      = 0x49
+	a2sHeaderChallenge = 0x41
+)
+
+func (p *A2SProber) Endpoint(host string, port int32) string {
+	return fmt.Sprintf("a2s://%s", net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+func (p *A2SProber) Probe(ctx context.Context, host string, port int32) (Result, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	resp, err := a2sRoundTrip(conn, a2sInfoRequest)
+	if err != nil {
+		return Result{}, err
+	}
+	// Newer servers answer with a challenge that must be echoed back.
+	if resp[0] == a2sHeaderChallenge {
+		if len(resp) < 5 {
+			return Result{}, decodeErr("short A2S challenge")
+		}
+		req := append(append([]byte{}, a2sInfoRequest...), resp[1:5]...)
+		if resp, err = a2sRoundTrip(conn, req); err != nil {
+			return Result{}, err
+		}
+	}
+	if resp[0] != a2sHeaderInfo {
+		return Result{}, decodeErr("unexpected A2S header 0x%02x", resp[0])
+	}
+	return parseA2SInfo(resp[1:])
+}
+
+// a2sRoundTrip sends req and returns the reply without the 0xFFFFFFFF prefix.
+func a2sRoundTrip(conn net.Conn, req []byte) ([]byte, error) {
+	if _, err := conn.Write(req); err != nil {
+		return nil, connErr(err)
+	}
+	buf := make([]byte, 1400)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, connErr(err)
+	}
+	if n < 5 || !bytes.Equal(buf[:4], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+		return nil, decodeErr("malformed A2S reply")
+	}
+	return buf[4:n], nil
+}
+
+// parseA2SInfo reads the player counts out of an A2S_INFO payload
+// (everything after the 0x49 header).
+func parseA2SInfo(b []byte) (Result, error) {
+	r := bytes.NewReader(b)
+	if _, err := r.ReadByte(); err != nil { // protocol
+		return Result{}, decodeErr("A2S_INFO: %v", err)
+	}
+	for _, field := range []string{"name", "map", "folder", "game"} {
+		if _, err := readCString(r); err != nil {
+			return Result{}, decodeErr("A2S_INFO %s: %v", field, err)
+		}
+	}
+	var info struct {
+		AppID      int16
+		Players    uint8
+		MaxPlayers uint8
+	}
+	if err := binary.Read(r, binary.LittleEndian, &info); err != nil {
+		return Result{}, decodeErr("A2S_INFO counts: %v", err)
+	}
+	return Result{Players: int32(info.Players), MaxPlayers: int32(info.MaxPlayers), HasCounts: true}, nil
+}
+
+func readCString(r *bytes.Reader) (string, error) {
+	var sb bytes.Buffer
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return sb.String(), nil
+		}
+		sb.WriteByte(c)
+	}
+}
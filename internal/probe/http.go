@@ -0,0 +1,54 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// maxStatusBody caps how much of a status response is read; the counts fit
+// in a few bytes.
+const maxStatusBody = 64 << 10
+
+// HTTPProber does GET http://host:port/Path and expects {"players":N,"maxPlayers":M}.
+type HTTPProber struct {
+	Client *http.Client
+	Path   string
+}
+
+func (p *HTTPProber) Endpoint(host string, port int32) string {
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(host, strconv.Itoa(int(port))), p.Path)
+}
+
+func (p *HTTPProber) Probe(ctx context.Context, host string, port int32) (Result, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Endpoint(host, port), nil)
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	// Read the body to the end (up to the cap) so the connection is reused.
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxStatusBody))
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Result{}, &Error{Reason: ReasonHTTPStatus, Err: fmt.Errorf("HTTP %d", resp.StatusCode)}
+	}
+	var body struct {
+		Players    int32 `json:"players"`
+		MaxPlayers int32 `json:"maxPlayers"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxStatusBody)).Decode(&body); err != nil {
+		return Result{}, decodeErr("decoding status body: %v", err)
+	}
+	return Result{Players: body.Players, MaxPlayers: body.MaxPlayers, HasCounts: true}, nil
+}
@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// MinecraftProber speaks the Minecraft Java Edition Server List Ping
+// (https://minecraft.wiki/w/Java_Edition_protocol/Server_List_Ping).
+type MinecraftProber struct{}
+
+// Any version works for a status ping; servers answer with their own.
+const minecraftProtocolVersion = 47
+
+func (p *MinecraftProber) Endpoint(host string, port int32) string {
+	return fmt.Sprintf("minecraft://%s", net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+func (p *MinecraftProber) Probe(ctx context.Context, host string, port int32) (Result, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Handshake (next state 1 = status), then an empty status request.
+	var hs bytes.Buffer
+	writeVarInt(&hs, 0x00)
+	writeVarInt(&hs, minecraftProtocolVersion)
+	writeVarInt(&hs, int32(len(host)))
+	hs.WriteString(host)
+	_ = binary.Write(&hs, binary.BigEndian, uint16(port))
+	writeVarInt(&hs, 1)
+	var out bytes.Buffer
+	writePacket(&out, hs.Bytes())
+	writePacket(&out, []byte{0x00})
+	if _, err := conn.Write(out.Bytes()); err != nil {
+		return Result{}, connErr(err)
+	}
+
+	r := bufio.NewReader(conn)
+	length, err := readVarInt(r)
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	if length <= 0 || length > 1<<21 {
+		return Result{}, decodeErr("SLP packet length %d", length)
+	}
+	pkt := make([]byte, length)
+	if _, err := io.ReadFull(r, pkt); err != nil {
+		return Result{}, connErr(err)
+	}
+	pr := bufio.NewReader(bytes.NewReader(pkt))
+	if id, err := readVarInt(pr); err != nil || id != 0x00 {
+		return Result{}, decodeErr("unexpected SLP packet id")
+	}
+	strLen, err := readVarInt(pr)
+	if err != nil || strLen < 0 {
+		return Result{}, decodeErr("SLP response length")
+	}
+	raw := make([]byte, strLen)
+	if _, err := io.ReadFull(pr, raw); err != nil {
+		return Result{}, decodeErr("SLP response: %v", err)
+	}
+	var status struct {
+		Players struct {
+			Max    int32 `json:"max"`
+			Online int32 `json:"online"`
+		} `json:"players"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return Result{}, decodeErr("SLP JSON: %v", err)
+	}
+	return Result{Players: status.Players.Online, MaxPlayers: status.Players.Max, HasCounts: true}, nil
+}
+
+func writePacket(w *bytes.Buffer, payload []byte) {
+	writeVarInt(w, int32(len(payload)))
+	w.Write(payload)
+}
+
+func writeVarInt(w *bytes.Buffer, v int32) {
+	u := uint32(v)
+	for {
+		if u&^0x7F == 0 {
+			w.WriteByte(byte(u))
+			return
+		}
+		w.WriteByte(byte(u&0x7F | 0x80))
+		u >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var v uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(v), nil
+		}
+	}
+	return 0, errors.New("VarInt too long")
+}
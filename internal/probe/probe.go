@@ -0,0 +1,102 @@
+// Package probe asks a running game server how many players it has.
+//
+// Each supported wire protocol implements StatusProber; New picks one from a
+// GameServer's statusProbe spec.
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+// DefaultTimeout bounds a single probe when the caller's context has no deadline.
+const DefaultTimeout = 2 * time.Second
+
+// Result is what a probe learned about the server.
+type Result struct {
+	Players    int32
+	MaxPlayers int32
+	// HasCounts is false for protocols that only prove liveness (TCP connect);
+	// Players/MaxPlayers are meaningless then.
+	HasCounts bool
+}
+
+// StatusProber queries one game server.
+type StatusProber interface {
+	// Probe contacts host:port and returns the server's player counts.
+	// Failures are returned as *Error.
+	Probe(ctx context.Context, host string, port int32) (Result, error)
+	// Endpoint is the address shown in GameServer.status.endpoint.
+	Endpoint(host string, port int32) string
+}
+
+// Failure reasons, also used as the metrics "reason" label.
+const (
+	ReasonConnection = "connection"
+	ReasonHTTPStatus = "http_status"
+	ReasonDecode     = "decode"
+)
+
+// Error is a failed probe with a coarse reason.
+type Error struct {
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Reason extracts the failure reason of err (ReasonConnection if unknown).
+func Reason(err error) string {
+	var pe *Error
+	if errors.As(err, &pe) {
+		return pe.Reason
+	}
+	return ReasonConnection
+}
+
+func connErr(err error) error { return &Error{Reason: ReasonConnection, Err: err} }
+
+func decodeErr(format string, args ...interface{}) error {
+	return &Error{Reason: ReasonDecode, Err: fmt.Errorf(format, args...)}
+}
+
+// New returns the prober selected by spec. A nil spec means HTTP JSON on pollPath.
+// httpc is reused by the HTTP prober so connections are pooled across polls.
+func New(spec *gamev1alpha1.StatusProbe, pollPath string, httpc *http.Client) (StatusProber, error) {
+	typ := gamev1alpha1.StatusProbeHTTP
+	if spec != nil && spec.Type != "" {
+		typ = spec.Type
+	}
+	switch typ {
+	case gamev1alpha1.StatusProbeHTTP:
+		if httpc == nil {
+			httpc = &http.Client{Timeout: DefaultTimeout}
+		}
+		if pollPath == "" {
+			pollPath = "/status"
+		}
+		return &HTTPProber{Client: httpc, Path: pollPath}, nil
+	case gamev1alpha1.StatusProbeA2S:
+		return &A2SProber{}, nil
+	case gamev1alpha1.StatusProbeMinecraft:
+		return &MinecraftProber{}, nil
+	case gamev1alpha1.StatusProbeTCP:
+		return &TCPProber{}, nil
+	default:
+		return nil, fmt.Errorf("unknown status probe type %q", typ)
+	}
+}
+
+// withTimeout applies DefaultTimeout when ctx has no deadline.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+// splitHostPort turns a listener address into the (host, port) the probers take.
+func splitHostPort(addr string) (string, int32) {
+	host, p, err := net.SplitHostPort(addr)
+	Expect(err).NotTo(HaveOccurred())
+	port, err := strconv.Atoi(p)
+	Expect(err).NotTo(HaveOccurred())
+	return host, int32(port)
+}
+
+func newProber(typ string) StatusProber {
+	p, err := New(&gamev1alpha1.StatusProbe{Type: typ}, "/status", nil)
+	Expect(err).NotTo(HaveOccurred())
+	return p
+}
+
+var _ = Describe("New", func() {
+	It("defaults to HTTP", func() {
+		p, err := New(nil, "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeAssignableToTypeOf(&HTTPProber{}))
+	})
+
+	It("rejects unknown types", func() {
+		_, err := New(&gamev1alpha1.StatusProbe{Type: "Gopher"}, "", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("HTTPProber", func() {
+	It("reads players and maxPlayers", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/status"))
+			_, _ = w.Write([]byte(`{"players":3,"maxPlayers":20}`))
+		}))
+		defer srv.Close()
+
+		host, port := splitHostPort(srv.Listener.Addr().String())
+		res, err := newProber(gamev1alpha1.StatusProbeHTTP).Probe(context.Background(), host, port)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(Result{Players: 3, MaxPlayers: 20, HasCounts: true}))
+	})
+
+	It("brackets IPv6 hosts in the URL", func() {
+		p := &HTTPProber{Path: "/status"}
+		Expect(p.Endpoint("fd00::7", 30000)).To(Equal("http://[fd00::7]:30000/status"))
+		Expect(p.Endpoint("10.0.0.7", 30000)).To(Equal("http://10.0.0.7:30000/status"))
+	})
+
+	It("reports non-2xx responses as http_status", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		host, port := splitHostPort(srv.Listener.Addr().String())
+		_, err := newProber(gamev1alpha1.StatusProbeHTTP).Probe(context.Background(), host, port)
+		Expect(Reason(err)).To(Equal(ReasonHTTPStatus))
+	})
+
+	It("reads no more than maxStatusBody", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(append(bytes.Repeat([]byte(" "), maxStatusBody), `{"players":1,"maxPlayers":8}`...))
+		}))
+		defer srv.Close()
+
+		host, port := splitHostPort(srv.Listener.Addr().String())
+		_, err := newProber(gamev1alpha1.StatusProbeHTTP).Probe(context.Background(), host, port)
+		Expect(Reason(err)).To(Equal(ReasonDecode))
+	})
+
+	It("reports bad JSON as decode", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`not json`))
+		}))
+		defer srv.Close()
+
+		host, port := splitHostPort(srv.Listener.Addr().String())
+		_, err := newProber(gamev1alpha1.StatusProbeHTTP).Probe(context.Background(), host, port)
+		Expect(Reason(err)).To(Equal(ReasonDecode))
+	})
+})
+
+var _ = Describe("TCPProber", func() {
+	It("succeeds when the port accepts connections, without counts", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			if c, err := ln.Accept(); err == nil {
+				_ = c.Close()
+			}
+		}()
+
+		host, port := splitHostPort(ln.Addr().String())
+		res, err := newProber(gamev1alpha1.StatusProbeTCP).Probe(context.Background(), host, port)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.HasCounts).To(BeFalse())
+	})
+
+	It("fails with connection when nothing listens", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		host, port := splitHostPort(ln.Addr().String())
+		Expect(ln.Close()).To(Succeed())
+
+		_, err = newProber(gamev1alpha1.StatusProbeTCP).Probe(context.Background(), host, port)
+		Expect(Reason(err)).To(Equal(ReasonConnection))
+	})
+})
+
+// fakeA2S answers A2S_INFO, first demanding a challenge like current Source servers.
+func fakeA2S(players, maxPlayers byte) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	challenge := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := buf[:n]
+			if !bytes.HasSuffix(req, challenge) {
+				_, _ = pc.WriteTo(append([]byte{0xFF, 0xFF, 0xFF, 0xFF, a2sHeaderChallenge}, challenge...), addr)
+				continue
+			}
+			var resp bytes.Buffer
+			resp.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, a2sHeaderInfo, 17})
+			for _, s := range []string{"My Server", "de_dust2", "csgo", "Counter-Strike"} {
+				resp.WriteString(s)
+				resp.WriteByte(0)
+			}
+			_ = binary.Write(&resp, binary.LittleEndian, int16(730))
+			resp.Write([]byte{players, maxPlayers, 0})
+			_, _ = pc.WriteTo(resp.Bytes(), addr)
+		}
+	}()
+	return pc
+}
+
+var _ = Describe("A2SProber", func() {
+	It("answers the challenge and reads the player counts", func() {
+		pc := fakeA2S(7, 24)
+		defer pc.Close()
+
+		host, port := splitHostPort(pc.LocalAddr().String())
+		res, err := newProber(gamev1alpha1.StatusProbeA2S).Probe(context.Background(), host, port)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(Result{Players: 7, MaxPlayers: 24, HasCounts: true}))
+	})
+})
+
+// fakeMinecraft serves one Server List Ping with the given counts.
+func fakeMinecraft(online, maxPlayers int) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for range 2 { // handshake, status request
+			n, err := readVarInt(r)
+			if err != nil {
+				return
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+				return
+			}
+		}
+		body := fmt.Sprintf(`{"version":{"name":"1.21","protocol":767},"players":{"max":%d,"online":%d},"description":{"text":"hi"}}`,
+			maxPlayers, online)
+		var pkt bytes.Buffer
+		writeVarInt(&pkt, 0x00)
+		writeVarInt(&pkt, int32(len(body)))
+		pkt.WriteString(body)
+		var out bytes.Buffer
+		writePacket(&out, pkt.Bytes())
+		_, _ = conn.Write(out.Bytes())
+	}()
+	return ln
+}
+
+var _ = Describe("MinecraftProber", func() {
+	It("reads online and max players from the status JSON", func() {
+		ln := fakeMinecraft(12, 50)
+		defer ln.Close()
+
+		host, port := splitHostPort(ln.Addr().String())
+		res, err := newProber(gamev1alpha1.StatusProbeMinecraft).Probe(context.Background(), host, port)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(Result{Players: 12, MaxPlayers: 50, HasCounts: true}))
+	})
+})
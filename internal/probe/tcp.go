@@ -0,0 +1,27 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// TCPProber only checks that the game port accepts a TCP connection.
+type TCPProber struct{}
+
+func (p *TCPProber) Endpoint(host string, port int32) string {
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+func (p *TCPProber) Probe(ctx context.Context, host string, port int32) (Result, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return Result{}, connErr(err)
+	}
+	_ = conn.Close()
+	return Result{}, nil
+}
@@ -0,0 +1,86 @@
+// Package schedule evaluates a GSDeployment's recurring scaling windows.
+//
+// A window opens at every activation of its cron expression and stays open
+// for durationSeconds. Windows may overlap; the first open one in spec order
+// is the active one.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// The manager image has no zoneinfo; embed it for time.LoadLocation.
+	_ "time/tzdata"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/robfig/cron/v3"
+)
+
+// Parse compiles a schedule's cron expression in its time zone. Only plain
+// 5-field expressions and the @hourly/@daily/... descriptors are accepted;
+// the zone comes from timeZone, not a CRON_TZ= prefix.
+func Parse(s *gamev1alpha1.ScalingSchedule) (*cron.SpecSchedule, error) {
+	if strings.Contains(s.Schedule, "TZ=") {
+		return nil, errors.New("set the zone with timeZone, not a TZ= prefix")
+	}
+	loc := time.UTC
+	if s.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.TimeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", s.TimeZone)
+		}
+	}
+	parsed, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := parsed.(*cron.SpecSchedule)
+	if !ok {
+		// @every has no fixed start, so it cannot anchor a window.
+		return nil, fmt.Errorf("%q is not a calendar schedule", s.Schedule)
+	}
+	spec.Location = loc
+	return spec, nil
+}
+
+// Window reports whether a window of length d is open at now and when it
+// next opens or closes. With overlapping windows the boundary is the end of
+// the latest start; the caller simply finds the next one open then.
+func Window(sched cron.Schedule, d time.Duration, now time.Time) (open bool, boundary time.Time) {
+	start := sched.Next(now.Add(-d))
+	if start.IsZero() || start.After(now) {
+		return false, start
+	}
+	for {
+		n := sched.Next(start)
+		if n.IsZero() || n.After(now) {
+			break
+		}
+		start = n
+	}
+	return true, start.Add(d)
+}
+
+// Resolve returns the first schedule open at now and the earliest time any
+// schedule opens or closes (zero when there is none). Invalid schedules are
+// skipped and reported in err.
+func Resolve(schedules []gamev1alpha1.ScalingSchedule, now time.Time) (active *gamev1alpha1.ScalingSchedule, next time.Time, err error) {
+	var errs []error
+	for i := range schedules {
+		s := &schedules[i]
+		sched, perr := Parse(s)
+		if perr != nil {
+			errs = append(errs, fmt.Errorf("schedule %q: %w", s.Name, perr))
+			continue
+		}
+		open, boundary := Window(sched, time.Duration(s.DurationSeconds)*time.Second, now)
+		if open && active == nil {
+			active = s
+		}
+		if !boundary.IsZero() && (next.IsZero() || boundary.Before(next)) {
+			next = boundary
+		}
+	}
+	return active, next, errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("Parse", func() {
+	It("reads the expression in the schedule's time zone", func() {
+		s, err := Parse(&gamev1alpha1.ScalingSchedule{Schedule: "0 18 * * *", TimeZone: "Europe/Berlin"})
+		Expect(err).NotTo(HaveOccurred())
+		// 18:00 CEST is 16:00 UTC.
+		next := s.Next(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC))
+		Expect(next.UTC()).To(Equal(time.Date(2026, 7, 1, 16, 0, 0, 0, time.UTC)))
+	})
+
+	It("defaults to UTC", func() {
+		s, err := Parse(&gamev1alpha1.ScalingSchedule{Schedule: "@daily"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Next(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC))).To(Equal(time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC)))
+	})
+
+	DescribeTable("rejects",
+		func(expr, tz string) {
+			_, err := Parse(&gamev1alpha1.ScalingSchedule{Schedule: expr, TimeZone: tz})
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("a malformed expression", "0 18 * *", ""),
+		Entry("an unknown zone", "0 18 * * *", "Mars/Olympus"),
+		Entry("a TZ prefix", "CRON_TZ=Europe/Berlin 0 18 * * *", ""),
+		Entry("@every", "@every 1h", ""),
+	)
+})
+
+var _ = Describe("Resolve", func() {
+	evening := gamev1alpha1.ScalingSchedule{Name: "evening", Schedule: "0 18 * * *", DurationSeconds: 4 * 3600, MinReplicas: ptr.To[int32](10)}
+	weekend := gamev1alpha1.ScalingSchedule{Name: "weekend", Schedule: "0 0 * * 6", DurationSeconds: 48 * 3600, MinReplicas: ptr.To[int32](6)}
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+
+	It("reports no window and the next opening outside any window", func() {
+		active, next, err := Resolve([]gamev1alpha1.ScalingSchedule{evening}, at(14, 12, 0)) // Wednesday
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeNil())
+		Expect(next).To(Equal(at(14, 18, 0)))
+	})
+
+	It("reports the open window and when it closes", func() {
+		active, next, err := Resolve([]gamev1alpha1.ScalingSchedule{evening}, at(14, 21, 30))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active.Name).To(Equal("evening"))
+		Expect(next).To(Equal(at(14, 22, 0)))
+	})
+
+	It("keeps a window open across midnight", func() {
+		s := evening
+		s.Schedule = "0 22 * * *"
+		active, next, _ := Resolve([]gamev1alpha1.ScalingSchedule{s}, at(15, 1, 0))
+		Expect(active).NotTo(BeNil())
+		Expect(next).To(Equal(at(15, 2, 0)))
+	})
+
+	It("prefers the first open window and requeues at the earliest boundary", func() {
+		// Saturday 19:00: both open; evening closes first.
+		active, next, err := Resolve([]gamev1alpha1.ScalingSchedule{weekend, evening}, at(17, 19, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active.Name).To(Equal("weekend"))
+		Expect(next).To(Equal(at(17, 22, 0)))
+	})
+
+	It("skips invalid schedules but reports them", func() {
+		bad := gamev1alpha1.ScalingSchedule{Name: "bad", Schedule: "nope", DurationSeconds: 60}
+		active, _, err := Resolve([]gamev1alpha1.ScalingSchedule{bad, evening}, at(14, 19, 0))
+		Expect(err).To(MatchError(ContainSubstring(`schedule "bad"`)))
+		Expect(active.Name).To(Equal("evening"))
+	})
+})
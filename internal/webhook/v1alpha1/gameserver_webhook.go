@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var gameserverlog = logf.Log.WithName("gameserver-resource")
+
+// SetupGameServerWebhookWithManager registers the GameServer validating webhook.
+func SetupGameServerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&gamev1alpha1.GameServer{}).
+		WithValidator(&GameServerCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-game-example-com-v1alpha1-gameserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=game.example.com,resources=gameservers,verbs=create;update,versions=v1alpha1,name=vgameserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// GameServerCustomValidator checks the port and keeps it immutable: the Pod
+// (hostNetwork) and the parent's port bookkeeping are both keyed on it. It
+// also checks the reservation annotations.
+type GameServerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &GameServerCustomValidator{}
+
+func (v *GameServerCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	gs, ok := obj.(*gamev1alpha1.GameServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServer object but got %T", obj)
+	}
+	gameserverlog.V(1).Info("validate create", "name", gs.GetName())
+	allErrs := validateGameServerSpec(&gs.Spec, field.NewPath("spec"))
+	allErrs = append(allErrs, validateReservation(gs.Annotations, field.NewPath("metadata", "annotations"))...)
+	return nil, toInvalid(gs, allErrs)
+}
+
+func (v *GameServerCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldGS, ok := oldObj.(*gamev1alpha1.GameServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServer object for the oldObj but got %T", oldObj)
+	}
+	gs, ok := newObj.(*gamev1alpha1.GameServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServer object for the newObj but got %T", newObj)
+	}
+	gameserverlog.V(1).Info("validate update", "name", gs.GetName())
+
+	allErrs := validateGameServerSpec(&gs.Spec, field.NewPath("spec"))
+	allErrs = append(allErrs, validateReservation(gs.Annotations, field.NewPath("metadata", "annotations"))...)
+	if gs.Spec.Port != oldGS.Spec.Port {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "port"),
+			fmt.Sprintf("is immutable (was %d)", oldGS.Spec.Port)))
+	}
+	return nil, toInvalid(gs, allErrs)
+}
+
+func (v *GameServerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateGameServerSpec(spec *gamev1alpha1.GameServerSpec, fld *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Port < 1 || spec.Port > 65535 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("port"), spec.Port, "must be between 1 and 65535"))
+	}
+	allErrs = append(allErrs, validateGameContainer(spec.Template, spec.Container, fld)...)
+	return allErrs
+}
+
+// validateReservation checks the reservation expiry is a time the controller
+// can read; a reserved-by without an expiry would never take effect.
+func validateReservation(anno map[string]string, fld *field.Path) field.ErrorList {
+	until, ok := anno[gamev1alpha1.ReservedUntilAnnotation]
+	if !ok {
+		if _, by := anno[gamev1alpha1.ReservedByAnnotation]; by {
+			return field.ErrorList{field.Required(fld.Key(gamev1alpha1.ReservedUntilAnnotation),
+				"is required with "+gamev1alpha1.ReservedByAnnotation)}
+		}
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, until); err != nil {
+		return field.ErrorList{field.Invalid(fld.Key(gamev1alpha1.ReservedUntilAnnotation), until,
+			"must be an RFC3339 time, e.g. 2025-01-01T12:00:00Z")}
+	}
+	return nil
+}
+
+// validateGameContainer makes sure an explicitly named game container exists in the template.
+func validateGameContainer(tmpl *corev1.PodTemplateSpec, name string, fld *field.Path) field.ErrorList {
+	if tmpl == nil || name == "" {
+		return nil
+	}
+	for _, c := range tmpl.Spec.Containers {
+		if c.Name == name {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotFound(fld.Child("container"), name)}
+}
+
+func toInvalid(gs *gamev1alpha1.GameServer, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(gamev1alpha1.GroupVersion.WithKind("GameServer").GroupKind(), gs.Name, allErrs)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("GameServer Webhook", func() {
+	var (
+		ctx       context.Context
+		validator GameServerCustomValidator
+		obj       *gamev1alpha1.GameServer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		obj = &gamev1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "gs", Namespace: "games"},
+			Spec:       gamev1alpha1.GameServerSpec{Port: 30001},
+		}
+	})
+
+	It("admits a valid GameServer", func() {
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects a port outside 1-65535", func() {
+		obj.Spec.Port = 0
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.port")))
+	})
+
+	It("rejects changing spec.port", func() {
+		newObj := obj.DeepCopy()
+		newObj.Spec.Port = 30002
+		_, err := validator.ValidateUpdate(ctx, obj, newObj)
+		Expect(err).To(MatchError(ContainSubstring("is immutable (was 30001)")))
+	})
+
+	It("rejects a game container missing from the template", func() {
+		obj.Spec.Container = "game"
+		obj.Spec.Template = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "server"}},
+		}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.container")))
+	})
+
+	It("rejects a reservation expiry that is not an RFC3339 time", func() {
+		obj.Annotations = map[string]string{
+			gamev1alpha1.ReservedByAnnotation:    "lobby-7",
+			gamev1alpha1.ReservedUntilAnnotation: "60s",
+		}
+		_, err := validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)
+		Expect(err).To(MatchError(ContainSubstring("metadata.annotations[game.example.com/reserved-until]")))
+
+		obj.Annotations[gamev1alpha1.ReservedUntilAnnotation] = "2025-01-01T12:00:30Z"
+		_, err = validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("requires an expiry with reserved-by", func() {
+		obj.Annotations = map[string]string{gamev1alpha1.ReservedByAnnotation: "lobby-7"}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("reserved-until")))
+	})
+})
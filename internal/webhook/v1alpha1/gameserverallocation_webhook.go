@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var gameserverallocationlog = logf.Log.WithName("gameserverallocation-resource")
+
+// SetupGameServerAllocationWebhookWithManager registers the GameServerAllocation validating webhook.
+func SetupGameServerAllocationWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&gamev1alpha1.GameServerAllocation{}).
+		WithValidator(&GameServerAllocationCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-game-example-com-v1alpha1-gameserverallocation,mutating=false,failurePolicy=fail,sideEffects=None,groups=game.example.com,resources=gameserverallocations,verbs=create;update,versions=v1alpha1,name=vgameserverallocation-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// GameServerAllocationCustomValidator keeps allocations create-only: the
+// controller answers each one once, so a changed spec would never be looked
+// at again. Metadata (labels, the release finalizer) may still change.
+type GameServerAllocationCustomValidator struct{}
+
+var _ webhook.CustomValidator = &GameServerAllocationCustomValidator{}
+
+func (v *GameServerAllocationCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	gsa, ok := obj.(*gamev1alpha1.GameServerAllocation)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServerAllocation object but got %T", obj)
+	}
+	gameserverallocationlog.V(1).Info("validate create", "name", gsa.GetName())
+	return nil, allocationInvalid(gsa, validateGameServerAllocationSpec(&gsa.Spec, field.NewPath("spec")))
+}
+
+func (v *GameServerAllocationCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldGSA, ok := oldObj.(*gamev1alpha1.GameServerAllocation)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServerAllocation object for the oldObj but got %T", oldObj)
+	}
+	gsa, ok := newObj.(*gamev1alpha1.GameServerAllocation)
+	if !ok {
+		return nil, fmt.Errorf("expected a GameServerAllocation object for the newObj but got %T", newObj)
+	}
+	gameserverallocationlog.V(1).Info("validate update", "name", gsa.GetName())
+
+	var allErrs field.ErrorList
+	if !equality.Semantic.DeepEqual(gsa.Spec, oldGSA.Spec) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"),
+			"is immutable: an allocation is answered once, create a new one instead"))
+	}
+	return nil, allocationInvalid(gsa, allErrs)
+}
+
+func (v *GameServerAllocationCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateGameServerAllocationSpec(spec *gamev1alpha1.GameServerAllocationSpec, fld *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.GSDeployment == "" {
+		allErrs = append(allErrs, field.Required(fld.Child("gsDeployment"), "names the GSDeployment to allocate from"))
+	}
+	if _, err := metav1.LabelSelectorAsSelector(spec.Selector); err != nil {
+		allErrs = append(allErrs, field.Invalid(fld.Child("selector"), spec.Selector, err.Error()))
+	}
+	return allErrs
+}
+
+func allocationInvalid(gsa *gamev1alpha1.GameServerAllocation, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(gamev1alpha1.GroupVersion.WithKind("GameServerAllocation").GroupKind(), gsa.Name, allErrs)
+}
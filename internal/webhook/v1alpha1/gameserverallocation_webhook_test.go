@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+var _ = Describe("GameServerAllocation Webhook", func() {
+	var (
+		ctx       context.Context
+		validator GameServerAllocationCustomValidator
+		obj       *gamev1alpha1.GameServerAllocation
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		obj = &gamev1alpha1.GameServerAllocation{
+			ObjectMeta: metav1.ObjectMeta{Name: "match", Namespace: "games"},
+			Spec:       gamev1alpha1.GameServerAllocationSpec{GSDeployment: "fleet"},
+		}
+	})
+
+	It("admits a valid allocation", func() {
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects a missing gsDeployment and a bad selector", func() {
+		obj.Spec.GSDeployment = ""
+		obj.Spec.Selector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "map", Operator: "Near"},
+		}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.gsDeployment")))
+		Expect(err).To(MatchError(ContainSubstring("spec.selector")))
+	})
+
+	It("rejects spec changes but not metadata changes", func() {
+		newObj := obj.DeepCopy()
+		newObj.Finalizers = []string{"game.example.com/allocation"}
+		_, err := validator.ValidateUpdate(ctx, obj, newObj)
+		Expect(err).NotTo(HaveOccurred())
+
+		newObj.Spec.GSDeployment = "other"
+		_, err = validator.ValidateUpdate(ctx, obj, newObj)
+		Expect(err).To(MatchError(ContainSubstring("spec: Forbidden")))
+	})
+})
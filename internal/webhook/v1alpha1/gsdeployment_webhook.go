@@ -0,0 +1,378 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+	"github.com/ahbeigi/gameserver-operator/internal/autoscaler"
+	"github.com/ahbeigi/gameserver-operator/internal/schedule"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var gsdeploymentlog = logf.Log.WithName("gsdeployment-resource")
+
+// SetupGSDeploymentWebhookWithManager registers the GSDeployment validating webhook.
+func SetupGSDeploymentWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&gamev1alpha1.GSDeployment{}).
+		WithValidator(&GSDeploymentCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-game-example-com-v1alpha1-gsdeployment,mutating=false,failurePolicy=fail,sideEffects=None,groups=game.example.com,resources=gsdeployments,verbs=create;update,versions=v1alpha1,name=vgsdeployment-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// GSDeploymentCustomValidator rejects fleets the controller could never satisfy.
+// Client is used to look for port range overlaps with sibling GSDeployments.
+type GSDeploymentCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &GSDeploymentCustomValidator{}
+
+func (v *GSDeploymentCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	gsd, ok := obj.(*gamev1alpha1.GSDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a GSDeployment object but got %T", obj)
+	}
+	gsdeploymentlog.V(1).Info("validate create", "name", gsd.GetName())
+	return nil, v.validate(ctx, gsd)
+}
+
+func (v *GSDeploymentCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	gsd, ok := newObj.(*gamev1alpha1.GSDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a GSDeployment object for the newObj but got %T", newObj)
+	}
+	gsdeploymentlog.V(1).Info("validate update", "name", gsd.GetName())
+	return nil, v.validate(ctx, gsd)
+}
+
+func (v *GSDeploymentCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *GSDeploymentCustomValidator) validate(ctx context.Context, gsd *gamev1alpha1.GSDeployment) error {
+	allErrs := validateGSDeploymentSpec(&gsd.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 && v.Client != nil {
+		errs, err := v.validatePortOverlap(ctx, gsd)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		allErrs = append(allErrs, errs...)
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(gamev1alpha1.GroupVersion.WithKind("GSDeployment").GroupKind(), gsd.Name, allErrs)
+}
+
+func validateGSDeploymentSpec(spec *gamev1alpha1.GSDeploymentSpec, fld *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.MinReplicas < 0 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("minReplicas"), spec.MinReplicas, "must be >= 0"))
+	}
+	if spec.MaxReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("maxReplicas"), spec.MaxReplicas, "must be >= 1"))
+	}
+	if spec.MinReplicas > spec.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(fld.Child("minReplicas"), spec.MinReplicas,
+			fmt.Sprintf("must not be greater than maxReplicas (%d)", spec.MaxReplicas)))
+	}
+
+	pr := fld.Child("portRange")
+	switch {
+	case spec.PortRange.Start < 1 || spec.PortRange.Start > 65535:
+		allErrs = append(allErrs, field.Invalid(pr.Child("start"), spec.PortRange.Start, "must be between 1 and 65535"))
+	case spec.PortRange.End < 1 || spec.PortRange.End > 65535:
+		allErrs = append(allErrs, field.Invalid(pr.Child("end"), spec.PortRange.End, "must be between 1 and 65535"))
+	case spec.PortRange.Start > spec.PortRange.End:
+		allErrs = append(allErrs, field.Invalid(pr, portRangeString(spec.PortRange),
+			fmt.Sprintf("start (%d) must not be greater than end (%d)", spec.PortRange.Start, spec.PortRange.End)))
+	case spec.PortPolicy == gamev1alpha1.PortPolicyPerNode:
+		// Ports are reused on every node; capacity depends on the node count.
+	default:
+		if size := spec.PortRange.End - spec.PortRange.Start + 1; size < portsNeeded(spec, spec.MaxReplicas) {
+			msg := fmt.Sprintf("holds %d ports but maxReplicas is %d; every GameServer needs its own port (or use portPolicy: PerNode)", size, spec.MaxReplicas)
+			if spec.UpdateStrategy.Type == gamev1alpha1.UpdateStrategyBlueGreen {
+				msg = fmt.Sprintf("holds %d ports but a BlueGreen preview runs next to the whole fleet, so it needs 2 x maxReplicas (%d) ports (or use portPolicy: PerNode)",
+					size, 2*spec.MaxReplicas)
+			}
+			allErrs = append(allErrs, field.Invalid(pr, portRangeString(spec.PortRange), msg))
+		}
+	}
+	switch spec.PortPolicy {
+	case "", gamev1alpha1.PortPolicyCluster, gamev1alpha1.PortPolicyPerNode:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fld.Child("portPolicy"), spec.PortPolicy,
+			[]string{gamev1alpha1.PortPolicyCluster, gamev1alpha1.PortPolicyPerNode}))
+	}
+
+	if p := spec.ScaleUpThresholdPercent; p != 0 && (p < 1 || p > 100) {
+		allErrs = append(allErrs, field.Invalid(fld.Child("scaleUpThresholdPercent"), p, "must be between 1 and 100"))
+	}
+	if spec.ScaleDownZeroSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("scaleDownZeroSeconds"), spec.ScaleDownZeroSeconds, "must be >= 0"))
+	}
+
+	allErrs = append(allErrs, validateScaling(spec.Scaling, fld.Child("scaling"))...)
+	allErrs = append(allErrs, validateSchedules(spec, fld.Child("schedules"))...)
+
+	us := fld.Child("updateStrategy")
+	switch spec.UpdateStrategy.Type {
+	case "", gamev1alpha1.UpdateStrategyNoDisruption, gamev1alpha1.UpdateStrategyBlueGreen,
+		gamev1alpha1.UpdateStrategyRecreate, gamev1alpha1.UpdateStrategyRollingUpdate:
+	case gamev1alpha1.UpdateStrategyCanary:
+		allErrs = append(allErrs, validateCanary(spec.UpdateStrategy.Canary, us.Child("canary"))...)
+	default:
+		allErrs = append(allErrs, field.NotSupported(us.Child("type"), spec.UpdateStrategy.Type,
+			[]string{gamev1alpha1.UpdateStrategyNoDisruption, gamev1alpha1.UpdateStrategyCanary, gamev1alpha1.UpdateStrategyBlueGreen,
+				gamev1alpha1.UpdateStrategyRecreate, gamev1alpha1.UpdateStrategyRollingUpdate}))
+	}
+	if spec.UpdateStrategy.Canary != nil && spec.UpdateStrategy.Type != gamev1alpha1.UpdateStrategyCanary {
+		allErrs = append(allErrs, field.Forbidden(us.Child("canary"), "only allowed when type is Canary"))
+	}
+	if spec.UpdateStrategy.BlueGreen != nil && spec.UpdateStrategy.Type != gamev1alpha1.UpdateStrategyBlueGreen {
+		allErrs = append(allErrs, field.Forbidden(us.Child("blueGreen"), "only allowed when type is BlueGreen"))
+	}
+	if spec.UpdateStrategy.DrainTimeoutSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(us.Child("drainTimeoutSeconds"), spec.UpdateStrategy.DrainTimeoutSeconds, "must be >= 0"))
+	}
+	allErrs = append(allErrs, validateIntOrPercent(spec.UpdateStrategy.MaxSurge, us.Child("maxSurge"))...)
+	allErrs = append(allErrs, validateIntOrPercent(spec.UpdateStrategy.MaxUnavailable, us.Child("maxUnavailable"))...)
+	if isZero(spec.UpdateStrategy.MaxSurge) && isZero(spec.UpdateStrategy.MaxUnavailable) {
+		allErrs = append(allErrs, field.Invalid(us.Child("maxUnavailable"), spec.UpdateStrategy.MaxUnavailable.String(),
+			"may not be 0 when maxSurge is 0; the rollout could never make progress"))
+	}
+
+	if spec.Parameters != nil && spec.Parameters.MaxPlayers != nil && *spec.Parameters.MaxPlayers < 1 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("parameters", "maxPlayers"), *spec.Parameters.MaxPlayers, "must be >= 1"))
+	}
+
+	allErrs = append(allErrs, validateGameContainer(spec.Template, spec.Container, fld)...)
+	return allErrs
+}
+
+// validateCanary checks a Canary strategy's steps: at least one, each either
+// a weight between 0 and 100 or a pause.
+func validateCanary(c *gamev1alpha1.CanaryStrategy, fld *field.Path) field.ErrorList {
+	if c == nil || len(c.Steps) == 0 {
+		return field.ErrorList{field.Required(fld.Child("steps"), "required when type is Canary")}
+	}
+	var allErrs field.ErrorList
+	for i, step := range c.Steps {
+		sf := fld.Child("steps").Index(i)
+		switch {
+		case step.SetWeight != nil && step.Pause != nil:
+			allErrs = append(allErrs, field.Invalid(sf, "", "setWeight and pause are mutually exclusive"))
+		case step.SetWeight != nil:
+			if w := *step.SetWeight; w < 0 || w > 100 {
+				allErrs = append(allErrs, field.Invalid(sf.Child("setWeight"), w, "must be between 0 and 100"))
+			}
+		case step.Pause != nil:
+			if d := step.Pause.DurationSeconds; d != nil && *d < 1 {
+				allErrs = append(allErrs, field.Invalid(sf.Child("pause", "durationSeconds"), *d, "must be >= 1"))
+			}
+		default:
+			allErrs = append(allErrs, field.Required(sf, "one of setWeight or pause is required"))
+		}
+	}
+	return allErrs
+}
+
+func validateScaling(sc *gamev1alpha1.Scaling, fld *field.Path) field.ErrorList {
+	if sc == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	switch sc.Policy {
+	case "", gamev1alpha1.ScalingPolicyThreshold, gamev1alpha1.ScalingPolicyBuffer, gamev1alpha1.ScalingPolicyUtilization:
+	case gamev1alpha1.ScalingPolicyWebhook:
+		if sc.Webhook == nil {
+			allErrs = append(allErrs, field.Required(fld.Child("webhook"), "required when policy is Webhook"))
+		} else if _, err := autoscaler.Endpoint(sc.Webhook); err != nil {
+			allErrs = append(allErrs, field.Invalid(fld.Child("webhook"), "", err.Error()))
+		} else if len(sc.Webhook.CABundle) > 0 && !x509.NewCertPool().AppendCertsFromPEM(sc.Webhook.CABundle) {
+			allErrs = append(allErrs, field.Invalid(fld.Child("webhook", "caBundle"), "", "contains no PEM certificates"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fld.Child("policy"), sc.Policy,
+			[]string{gamev1alpha1.ScalingPolicyThreshold, gamev1alpha1.ScalingPolicyBuffer, gamev1alpha1.ScalingPolicyWebhook, gamev1alpha1.ScalingPolicyUtilization}))
+	}
+	if p := sc.TargetUtilizationPercent; p != 0 && (p < 1 || p > 100) {
+		allErrs = append(allErrs, field.Invalid(fld.Child("targetUtilizationPercent"), p, "must be between 1 and 100"))
+	}
+	if sc.MaxScaleUpStep != nil && *sc.MaxScaleUpStep < 1 {
+		allErrs = append(allErrs, field.Invalid(fld.Child("maxScaleUpStep"), *sc.MaxScaleUpStep, "must be >= 1"))
+	}
+	allErrs = append(allErrs, validateBufferSize(sc.BufferSize, fld.Child("bufferSize"))...)
+	if sc.Behavior != nil {
+		allErrs = append(allErrs, validateScalingRules(sc.Behavior.ScaleUp, fld.Child("behavior", "scaleUp"))...)
+		allErrs = append(allErrs, validateScalingRules(sc.Behavior.ScaleDown, fld.Child("behavior", "scaleDown"))...)
+	}
+	return allErrs
+}
+
+func validateScalingRules(rules *gamev1alpha1.ScalingRules, fld *field.Path) field.ErrorList {
+	if rules == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if w := rules.StabilizationWindowSeconds; w != nil && (*w < 0 || *w > 3600) {
+		allErrs = append(allErrs, field.Invalid(fld.Child("stabilizationWindowSeconds"), *w, "must be between 0 and 3600"))
+	}
+	switch rules.SelectPolicy {
+	case "", gamev1alpha1.SelectPolicyMax, gamev1alpha1.SelectPolicyMin, gamev1alpha1.SelectPolicyDisabled:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fld.Child("selectPolicy"), rules.SelectPolicy,
+			[]string{gamev1alpha1.SelectPolicyMax, gamev1alpha1.SelectPolicyMin, gamev1alpha1.SelectPolicyDisabled}))
+	}
+	for i, p := range rules.Policies {
+		pf := fld.Child("policies").Index(i)
+		switch p.Type {
+		case gamev1alpha1.ScalingRateServers, gamev1alpha1.ScalingRatePercent:
+		default:
+			allErrs = append(allErrs, field.NotSupported(pf.Child("type"), p.Type,
+				[]string{gamev1alpha1.ScalingRateServers, gamev1alpha1.ScalingRatePercent}))
+		}
+		if p.Value < 1 {
+			allErrs = append(allErrs, field.Invalid(pf.Child("value"), p.Value, "must be >= 1"))
+		}
+		if p.PeriodSeconds < 1 || p.PeriodSeconds > 1800 {
+			allErrs = append(allErrs, field.Invalid(pf.Child("periodSeconds"), p.PeriodSeconds, "must be between 1 and 1800"))
+		}
+	}
+	return allErrs
+}
+
+func validateBufferSize(v *intstr.IntOrString, fld *field.Path) field.ErrorList {
+	if errs := validateIntOrPercent(v, fld); len(errs) > 0 {
+		return errs
+	}
+	if v != nil && v.Type == intstr.String {
+		// 100% would mean a fleet made only of empty servers, i.e. unbounded.
+		if pct, _ := intstr.GetScaledValueFromIntOrPercent(v, 100, false); pct >= 100 {
+			return field.ErrorList{field.Invalid(fld, v.String(), "percentage must be below 100%")}
+		}
+	}
+	return nil
+}
+
+// validateSchedules checks each window on its own and against the spec it
+// overrides: the bounds it produces must be as valid as the spec's own.
+func validateSchedules(spec *gamev1alpha1.GSDeploymentSpec, fld *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i := range spec.Schedules {
+		s := &spec.Schedules[i]
+		p := fld.Index(i)
+		switch {
+		case s.Name == "":
+			allErrs = append(allErrs, field.Required(p.Child("name"), ""))
+		case names[s.Name]:
+			allErrs = append(allErrs, field.Duplicate(p.Child("name"), s.Name))
+		}
+		names[s.Name] = true
+		if _, err := schedule.Parse(s); err != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("schedule"), s.Schedule, err.Error()))
+		}
+		if s.DurationSeconds < 1 || s.DurationSeconds > 7*24*3600 {
+			allErrs = append(allErrs, field.Invalid(p.Child("durationSeconds"), s.DurationSeconds, "must be between 1 and 604800 (7 days)"))
+		}
+
+		minR, maxR := spec.MinReplicas, spec.MaxReplicas
+		if s.MinReplicas != nil {
+			minR = *s.MinReplicas
+			if minR < 0 {
+				allErrs = append(allErrs, field.Invalid(p.Child("minReplicas"), minR, "must be >= 0"))
+			}
+		}
+		if s.MaxReplicas != nil {
+			maxR = *s.MaxReplicas
+			if maxR < 1 {
+				allErrs = append(allErrs, field.Invalid(p.Child("maxReplicas"), maxR, "must be >= 1"))
+			}
+			if spec.PortPolicy != gamev1alpha1.PortPolicyPerNode {
+				if size := spec.PortRange.End - spec.PortRange.Start + 1; size > 0 && portsNeeded(spec, maxR) > size {
+					allErrs = append(allErrs, field.Invalid(p.Child("maxReplicas"), maxR,
+						fmt.Sprintf("portRange holds only %d ports, %d needed", size, portsNeeded(spec, maxR))))
+				}
+			}
+		}
+		if minR > maxR {
+			allErrs = append(allErrs, field.Invalid(p.Child("minReplicas"), minR,
+				fmt.Sprintf("must not be greater than maxReplicas (%d) while the window is open", maxR)))
+		}
+		allErrs = append(allErrs, validateBufferSize(s.BufferSize, p.Child("bufferSize"))...)
+	}
+	return allErrs
+}
+
+// validatePortOverlap rejects a portRange that intersects another GSDeployment's
+// range in the same namespace: both fleets would hand out the same host ports.
+func (v *GSDeploymentCustomValidator) validatePortOverlap(ctx context.Context, gsd *gamev1alpha1.GSDeployment) (field.ErrorList, error) {
+	var list gamev1alpha1.GSDeploymentList
+	if err := v.Client.List(ctx, &list, client.InNamespace(gsd.Namespace)); err != nil {
+		return nil, err
+	}
+	var allErrs field.ErrorList
+	for _, other := range list.Items {
+		if other.Name == gsd.Name {
+			continue
+		}
+		if gsd.Spec.PortRange.Start <= other.Spec.PortRange.End && other.Spec.PortRange.Start <= gsd.Spec.PortRange.End {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "portRange"), portRangeString(gsd.Spec.PortRange),
+				fmt.Sprintf("overlaps GSDeployment %q (%d-%d)", other.Name, other.Spec.PortRange.Start, other.Spec.PortRange.End)))
+		}
+	}
+	return allErrs, nil
+}
+
+// validateIntOrPercent accepts a non-negative integer or a percentage like "25%".
+func validateIntOrPercent(v *intstr.IntOrString, fld *field.Path) field.ErrorList {
+	if v == nil {
+		return nil
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(v, 100, false)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fld, v.String(), "must be an integer or a percentage (e.g. 25%)")}
+	}
+	if n < 0 {
+		return field.ErrorList{field.Invalid(fld, v.String(), "must be >= 0")}
+	}
+	return nil
+}
+
+// isZero is true only for an explicit 0 or "0%" (nil means "use the default").
+func isZero(v *intstr.IntOrString) bool {
+	if v == nil {
+		return false
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(v, 100, false)
+	return err == nil && n == 0
+}
+
+// portsNeeded is how many ports a Cluster-policy fleet of maxReplicas servers
+// may hold at once. A BlueGreen preview is created next to the whole active
+// fleet, so that strategy needs twice as many.
+func portsNeeded(spec *gamev1alpha1.GSDeploymentSpec, maxReplicas int32) int32 {
+	if spec.UpdateStrategy.Type == gamev1alpha1.UpdateStrategyBlueGreen {
+		return 2 * maxReplicas
+	}
+	return maxReplicas
+}
+
+func portRangeString(pr gamev1alpha1.PortRange) string {
+	return fmt.Sprintf("%d-%d", pr.Start, pr.End)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gamev1alpha1 "github.com/ahbeigi/gameserver-operator/api/v1alpha1"
+)
+
+func validGSDeployment(name string, start, end int32) *gamev1alpha1.GSDeployment {
+	return &gamev1alpha1.GSDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "games"},
+		Spec: gamev1alpha1.GSDeploymentSpec{
+			MinReplicas: 1,
+			MaxReplicas: 3,
+			PortRange:   gamev1alpha1.PortRange{Start: start, End: end},
+		},
+	}
+}
+
+var _ = Describe("GSDeployment Webhook", func() {
+	var (
+		ctx       context.Context
+		validator GSDeploymentCustomValidator
+		obj       *gamev1alpha1.GSDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		utilruntime.Must(gamev1alpha1.AddToScheme(scheme))
+		other := validGSDeployment("other", 31000, 31010)
+		validator = GSDeploymentCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(other).Build(),
+		}
+		obj = validGSDeployment("fleet", 30000, 30005)
+	})
+
+	It("admits a valid fleet", func() {
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects minReplicas > maxReplicas", func() {
+		obj.Spec.MinReplicas = 5
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.minReplicas")))
+	})
+
+	It("rejects an inverted portRange", func() {
+		obj.Spec.PortRange = gamev1alpha1.PortRange{Start: 30005, End: 30000}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("must not be greater than end")))
+	})
+
+	It("rejects a portRange smaller than maxReplicas", func() {
+		obj.Spec.PortRange = gamev1alpha1.PortRange{Start: 30000, End: 30001}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("holds 2 ports but maxReplicas is 3")))
+	})
+
+	It("allows a small portRange with portPolicy PerNode", func() {
+		obj.Spec.PortRange = gamev1alpha1.PortRange{Start: 30000, End: 30001}
+		obj.Spec.PortPolicy = gamev1alpha1.PortPolicyPerNode
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects scaleUpThresholdPercent outside 1-100", func() {
+		obj.Spec.ScaleUpThresholdPercent = 120
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaleUpThresholdPercent")))
+	})
+
+	It("rejects an unknown updateStrategy.type", func() {
+		obj.Spec.UpdateStrategy.Type = "YOLO"
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.updateStrategy.type")))
+	})
+
+	It("accepts a Canary strategy with weights and pauses", func() {
+		obj.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyCanary
+		obj.Spec.UpdateStrategy.Canary = &gamev1alpha1.CanaryStrategy{Steps: []gamev1alpha1.CanaryStep{
+			{SetWeight: ptr.To[int32](10)},
+			{Pause: &gamev1alpha1.CanaryPause{DurationSeconds: ptr.To[int32](1800)}},
+			{SetWeight: ptr.To[int32](50)},
+			{Pause: &gamev1alpha1.CanaryPause{}},
+		}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects a Canary strategy without steps or with ambiguous ones", func() {
+		obj.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyCanary
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.updateStrategy.canary.steps")))
+
+		obj.Spec.UpdateStrategy.Canary = &gamev1alpha1.CanaryStrategy{Steps: []gamev1alpha1.CanaryStep{
+			{SetWeight: ptr.To[int32](10), Pause: &gamev1alpha1.CanaryPause{}},
+			{},
+			{SetWeight: ptr.To[int32](150)},
+		}}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("mutually exclusive")))
+		Expect(err).To(MatchError(ContainSubstring("spec.updateStrategy.canary.steps[1]")))
+		Expect(err).To(MatchError(ContainSubstring("spec.updateStrategy.canary.steps[2].setWeight")))
+	})
+
+	It("accepts BlueGreen, and its settings only with that type", func() {
+		obj.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyBlueGreen
+		obj.Spec.UpdateStrategy.BlueGreen = &gamev1alpha1.BlueGreenStrategy{AutoPromotionEnabled: ptr.To(false)}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyNoDisruption
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.updateStrategy.blueGreen")))
+	})
+
+	It("requires ports for the BlueGreen preview next to the whole fleet", func() {
+		obj.Spec.MaxReplicas = 4 // 6 ports: enough for the fleet, not for its preview too
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.UpdateStrategy.Type = gamev1alpha1.UpdateStrategyBlueGreen
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("needs 2 x maxReplicas (8) ports")))
+
+		obj.Spec.MaxReplicas = 3
+		obj.Spec.Schedules = []gamev1alpha1.ScalingSchedule{{Name: "evening", Schedule: "0 18 * * *",
+			DurationSeconds: 3600, MaxReplicas: ptr.To[int32](4)}}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("portRange holds only 6 ports, 8 needed")))
+
+		obj.Spec.MaxReplicas = 4
+		obj.Spec.PortPolicy = gamev1alpha1.PortPolicyPerNode
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("accepts percentages for maxSurge and maxUnavailable", func() {
+		surge, unavailable := intstr.FromString("25%"), intstr.FromInt32(1)
+		obj.Spec.UpdateStrategy.MaxSurge = &surge
+		obj.Spec.UpdateStrategy.MaxUnavailable = &unavailable
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects maxSurge and maxUnavailable both 0", func() {
+		zero := intstr.FromInt32(0)
+		obj.Spec.UpdateStrategy.MaxSurge = &zero
+		obj.Spec.UpdateStrategy.MaxUnavailable = &zero
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("may not be 0 when maxSurge is 0")))
+	})
+
+	It("accepts a Buffer policy and rejects a 100% buffer", func() {
+		size := intstr.FromString("30%")
+		obj.Spec.Scaling = &gamev1alpha1.Scaling{Policy: gamev1alpha1.ScalingPolicyBuffer, BufferSize: &size}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		size = intstr.FromString("100%")
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.bufferSize")))
+	})
+
+	It("checks the Utilization policy's target and step", func() {
+		obj.Spec.Scaling = &gamev1alpha1.Scaling{Policy: gamev1alpha1.ScalingPolicyUtilization, TargetUtilizationPercent: 75, MaxScaleUpStep: ptr.To[int32](3)}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.Scaling.TargetUtilizationPercent = 120
+		obj.Spec.Scaling.MaxScaleUpStep = ptr.To[int32](0)
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.targetUtilizationPercent")))
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.maxScaleUpStep")))
+	})
+
+	It("validates scaling behavior rules", func() {
+		obj.Spec.Scaling = &gamev1alpha1.Scaling{Behavior: &gamev1alpha1.ScalingBehavior{
+			ScaleDown: &gamev1alpha1.ScalingRules{
+				StabilizationWindowSeconds: ptr.To[int32](300),
+				Policies: []gamev1alpha1.ScalingRatePolicy{
+					{Type: gamev1alpha1.ScalingRateServers, Value: 2, PeriodSeconds: 60},
+					{Type: gamev1alpha1.ScalingRatePercent, Value: 10, PeriodSeconds: 60},
+				},
+			},
+		}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.Scaling.Behavior.ScaleUp = &gamev1alpha1.ScalingRules{
+			SelectPolicy: "Average",
+			Policies:     []gamev1alpha1.ScalingRatePolicy{{Type: "Pods", Value: 0, PeriodSeconds: 3600}},
+		}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.behavior.scaleUp.selectPolicy")))
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.behavior.scaleUp.policies[0].type")))
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.behavior.scaleUp.policies[0].value")))
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.behavior.scaleUp.policies[0].periodSeconds")))
+	})
+
+	It("requires a usable webhook for the Webhook policy", func() {
+		obj.Spec.Scaling = &gamev1alpha1.Scaling{Policy: gamev1alpha1.ScalingPolicyWebhook}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.webhook: Required")))
+
+		url := "http://forecaster.liveops:8080/scale"
+		obj.Spec.Scaling.Webhook = &admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: []byte("nope")}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.scaling.webhook.caBundle")))
+
+		obj.Spec.Scaling.Webhook.CABundle = nil
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("validates scaling schedules against the spec they override", func() {
+		evening := gamev1alpha1.ScalingSchedule{
+			Name: "evening", Schedule: "0 18 * * 1-5", TimeZone: "Europe/Berlin", DurationSeconds: 14400,
+			MinReplicas: ptr.To[int32](5), MaxReplicas: ptr.To[int32](6),
+		}
+		obj.Spec.Schedules = []gamev1alpha1.ScalingSchedule{evening}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		bad := evening
+		bad.Schedule = "0 18 * *"
+		bad.MaxReplicas = nil // min 5 > spec maxReplicas 3
+		obj.Spec.Schedules = []gamev1alpha1.ScalingSchedule{evening, bad}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.schedules[1].name: Duplicate")))
+		Expect(err).To(MatchError(ContainSubstring("spec.schedules[1].schedule")))
+		Expect(err).To(MatchError(ContainSubstring("spec.schedules[1].minReplicas")))
+
+		big := evening
+		big.MaxReplicas = ptr.To[int32](7)
+		obj.Spec.Schedules = []gamev1alpha1.ScalingSchedule{big}
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).To(MatchError(ContainSubstring("portRange holds only 6 ports, 7 needed")))
+	})
+
+	It("rejects a portRange overlapping another GSDeployment", func() {
+		obj.Spec.PortRange = gamev1alpha1.PortRange{Start: 31005, End: 31020}
+		_, err := validator.ValidateUpdate(ctx, obj, obj)
+		Expect(err).To(MatchError(ContainSubstring(`overlaps GSDeployment "other"`)))
+	})
+})